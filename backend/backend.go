@@ -0,0 +1,27 @@
+// Package backend describes the predictor lifecycle used by the MXNet agent
+// without depending on libmxnet. mxnet.Predictor implements Backend on top of
+// the C predict API; Fake implements it in-process for tests.
+package backend
+
+import (
+	"context"
+
+	"github.com/rai-project/dlframework/framework/options"
+	gotensor "gorgonia.org/tensor"
+)
+
+// Backend is a predictor that can run forward passes and return their outputs
+type Backend interface {
+	// GetOptions returns the options the backend was created with
+	GetOptions() *options.Options
+	// Predict runs a forward pass over data, one tensor per input node
+	Predict(ctx context.Context, data []*gotensor.Dense) error
+	// ReadPredictionOutputs returns the outputs of the last forward pass,
+	// one tensor per output node
+	ReadPredictionOutputs(ctx context.Context) ([]gotensor.Tensor, error)
+	// Close releases the resources held by the backend
+	Close() error
+}
+
+// Constructor creates a Backend from predictor options
+type Constructor func(ctx context.Context, opts ...options.Option) (Backend, error)
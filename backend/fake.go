@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/go-mxnet/utils"
+	gotensor "gorgonia.org/tensor"
+)
+
+// Fake is an in-process Backend that does not need libmxnet.
+// Its outputs are deterministic: element i of every output is float(i%10)/10,
+// converted to the output node's dtype.
+// The inputs are checked as the mxnet predictor checks them, and a Fake is
+// safe for concurrent use.
+type Fake struct {
+	options   *options.Options
+	mu        sync.Mutex
+	predicted bool
+	closed    bool
+}
+
+// the input dtypes accepted by the mxnet predictor
+var fakeDtypes = []gotensor.Dtype{
+	gotensor.Float32,
+	gotensor.Float64,
+	utils.Float16Dtype,
+	gotensor.Int32,
+	gotensor.Int8,
+	gotensor.Uint8,
+}
+
+// NewFake creates a fake backend. Only the input nodes are required; the
+// graph and the weights are ignored.
+func NewFake(ctx context.Context, opts ...options.Option) (Backend, error) {
+	options := options.New(opts...)
+	if len(options.InputNodes()) == 0 {
+		return nil, errors.New("no input nodes found")
+	}
+	return &Fake{options: options}, nil
+}
+
+func (f *Fake) GetOptions() *options.Options {
+	return f.options
+}
+
+func (f *Fake) Predict(ctx context.Context, data []*gotensor.Dense) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return errors.New("predictor is closed")
+	}
+	if len(data) == 0 {
+		return errors.New("intput data nil or empty")
+	}
+	inputNodes := f.options.InputNodes()
+	if len(data) != len(inputNodes) {
+		return errors.Errorf("expecting %d inputs, got %d", len(inputNodes), len(data))
+	}
+	for ii, inputNode := range inputNodes {
+		if inputNode.Key == "" {
+			return errors.New("expecting a valid (non-empty) input layer name")
+		}
+		if err := validateFakeInput(inputNode, data[ii]); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.predicted = true
+	return nil
+}
+
+func (f *Fake) ReadPredictionOutputs(ctx context.Context) ([]gotensor.Tensor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, errors.New("predictor is closed")
+	}
+	if !f.predicted {
+		return nil, errors.New("predict was not called")
+	}
+
+	outputNodes := f.options.OutputNodes()
+	res := make([]gotensor.Tensor, len(outputNodes))
+	for ii, node := range outputNodes {
+		tensor, err := fakeOutput(node, int(f.options.BatchSize()))
+		if err != nil {
+			return nil, err
+		}
+		res[ii] = tensor
+	}
+	return res, nil
+}
+
+func (f *Fake) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// validateFakeInput checks an input as the mxnet predictor does: the dtype must
+// be supported and match the node's dtype when it is set, the element count must
+// match the node's shape, and a tensor of the node's rank must have its shape.
+// Nodes without a shape accept any shape.
+func validateFakeInput(node options.Node, input *gotensor.Dense) error {
+	if input == nil {
+		return errors.Errorf("input %s is nil", node.Key)
+	}
+	supported := false
+	for _, dt := range fakeDtypes {
+		if dt == input.Dtype() {
+			supported = true
+		}
+	}
+	if !supported {
+		return errors.Errorf("input %s has unsupported dtype %v", node.Key, input.Dtype())
+	}
+	if node.Dtype.Type != nil && node.Dtype != input.Dtype() {
+		return errors.Errorf("input %s has dtype %v, expecting %v", node.Key, input.Dtype(), node.Dtype)
+	}
+	if len(node.Shape) == 0 {
+		return nil
+	}
+	size := 1
+	for _, dim := range node.Shape {
+		size *= dim
+	}
+	if input.Size() != size {
+		return errors.Errorf("input %s has %d elements, expecting %d for shape %v", node.Key, input.Size(), size, node.Shape)
+	}
+	shape := input.Shape()
+	if len(shape) != len(node.Shape) {
+		return nil
+	}
+	for ii, dim := range shape {
+		if dim != node.Shape[ii] {
+			return errors.Errorf("input %s has shape %v, expecting %v", node.Key, shape, node.Shape)
+		}
+	}
+	return nil
+}
+
+// fakeOutput creates the output tensor for node. The node's shape is used
+// when it is set, otherwise the output is a single value per batch element.
+func fakeOutput(node options.Node, batchSize int) (gotensor.Tensor, error) {
+	shape := node.Shape
+	if len(shape) == 0 {
+		shape = []int{batchSize, 1}
+	}
+	size := 1
+	for _, dim := range shape {
+		size *= dim
+	}
+
+	dtype := node.Dtype
+	if dtype.Type == nil {
+		dtype = gotensor.Float32
+	}
+
+	var backing interface{}
+	switch dtype {
+	case gotensor.Float32:
+		data := make([]float32, size)
+		for ii := range data {
+			data[ii] = float32(ii%10) / 10
+		}
+		backing = data
+	case gotensor.Float64:
+		data := make([]float64, size)
+		for ii := range data {
+			data[ii] = float64(ii%10) / 10
+		}
+		backing = data
+	case utils.Float16Dtype:
+		data := make([]utils.Float16, size)
+		for ii := range data {
+			data[ii] = utils.Float16FromFloat32(float32(ii%10) / 10)
+		}
+		backing = data
+	case gotensor.Int32:
+		data := make([]int32, size)
+		for ii := range data {
+			data[ii] = int32(ii % 10)
+		}
+		backing = data
+	case gotensor.Int8:
+		data := make([]int8, size)
+		for ii := range data {
+			data[ii] = int8(ii % 10)
+		}
+		backing = data
+	case gotensor.Uint8:
+		data := make([]uint8, size)
+		for ii := range data {
+			data[ii] = uint8(ii % 10)
+		}
+		backing = data
+	default:
+		return nil, errors.Errorf("fake backend does not support %v outputs", dtype)
+	}
+
+	return gotensor.New(
+		gotensor.Of(dtype),
+		gotensor.WithShape(shape...),
+		gotensor.WithBacking(backing),
+	), nil
+}
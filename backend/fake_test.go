@@ -0,0 +1,225 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/go-mxnet/utils"
+	gotensor "gorgonia.org/tensor"
+)
+
+func newTestFake(t *testing.T, outputs ...options.Node) Backend {
+	f, err := NewFake(context.Background(),
+		options.BatchSize(2),
+		options.InputNodes([]options.Node{{Key: "data", Shape: []int{2, 3}}}),
+		options.OutputNodes(outputs),
+	)
+	if err != nil {
+		t.Fatalf("NewFake: %v", err)
+	}
+	return f
+}
+
+func testInput() []*gotensor.Dense {
+	return []*gotensor.Dense{
+		gotensor.New(gotensor.WithShape(2, 3), gotensor.WithBacking(make([]float32, 6))),
+	}
+}
+
+func TestNewFakeWithoutInputNodes(t *testing.T) {
+	if _, err := NewFake(context.Background()); err == nil {
+		t.Fatal("NewFake without input nodes should fail")
+	}
+}
+
+func TestFakeOutputs(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  options.Node
+		shape []int
+		data  interface{}
+	}{
+		{
+			name:  "default",
+			node:  options.Node{},
+			shape: []int{2, 1},
+			data:  []float32{0, 0.1},
+		},
+		{
+			name:  "float32",
+			node:  options.Node{Shape: []int{2, 6}, Dtype: gotensor.Float32},
+			shape: []int{2, 6},
+			data:  []float32{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 0, 0.1},
+		},
+		{
+			name:  "float64",
+			node:  options.Node{Shape: []int{3}, Dtype: gotensor.Float64},
+			shape: []int{3},
+			data:  []float64{0, 0.1, 0.2},
+		},
+		{
+			name:  "float16",
+			node:  options.Node{Shape: []int{2}, Dtype: utils.Float16Dtype},
+			shape: []int{2},
+			data:  []utils.Float16{0, 0x2e66},
+		},
+		{
+			name:  "int32",
+			node:  options.Node{Shape: []int{12}, Dtype: gotensor.Int32},
+			shape: []int{12},
+			data:  []int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0, 1},
+		},
+		{
+			name:  "int8",
+			node:  options.Node{Shape: []int{2, 2}, Dtype: gotensor.Int8},
+			shape: []int{2, 2},
+			data:  []int8{0, 1, 2, 3},
+		},
+		{
+			name:  "uint8",
+			node:  options.Node{Shape: []int{2}, Dtype: gotensor.Uint8},
+			shape: []int{2},
+			data:  []uint8{0, 1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTestFake(t, tc.node)
+			defer f.Close()
+
+			if err := f.Predict(context.Background(), testInput()); err != nil {
+				t.Fatalf("Predict: %v", err)
+			}
+			outputs, err := f.ReadPredictionOutputs(context.Background())
+			if err != nil {
+				t.Fatalf("ReadPredictionOutputs: %v", err)
+			}
+			if len(outputs) != 1 {
+				t.Fatalf("got %d outputs, expecting 1", len(outputs))
+			}
+			if shape := []int(outputs[0].Shape()); !reflect.DeepEqual(shape, tc.shape) {
+				t.Errorf("got shape %v, expecting %v", shape, tc.shape)
+			}
+			if data := outputs[0].Data(); !reflect.DeepEqual(data, tc.data) {
+				t.Errorf("got data %v, expecting %v", data, tc.data)
+			}
+		})
+	}
+}
+
+func TestFakeUnsupportedDtype(t *testing.T) {
+	f := newTestFake(t, options.Node{Dtype: gotensor.Complex64})
+	if err := f.Predict(context.Background(), testInput()); err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+	if _, err := f.ReadPredictionOutputs(context.Background()); err == nil {
+		t.Fatal("reading a complex64 output should fail")
+	}
+}
+
+func TestFakePredictErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		data []*gotensor.Dense
+	}{
+		{name: "no data", ctx: context.Background(), data: nil},
+		{name: "too many inputs", ctx: context.Background(), data: append(testInput(), testInput()...)},
+		{name: "nil input", ctx: context.Background(), data: []*gotensor.Dense{nil}},
+		{name: "element count", ctx: context.Background(), data: []*gotensor.Dense{
+			gotensor.New(gotensor.WithShape(2, 2), gotensor.WithBacking(make([]float32, 4))),
+		}},
+		{name: "shape", ctx: context.Background(), data: []*gotensor.Dense{
+			gotensor.New(gotensor.WithShape(3, 2), gotensor.WithBacking(make([]float32, 6))),
+		}},
+		{name: "unsupported dtype", ctx: context.Background(), data: []*gotensor.Dense{
+			gotensor.New(gotensor.WithShape(2, 3), gotensor.WithBacking(make([]complex64, 6))),
+		}},
+		{name: "canceled", ctx: canceled, data: testInput()},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTestFake(t)
+			if err := f.Predict(tc.ctx, tc.data); err == nil {
+				t.Fatal("Predict should fail")
+			}
+			if _, err := f.ReadPredictionOutputs(context.Background()); err == nil {
+				t.Fatal("ReadPredictionOutputs should fail after a failed Predict")
+			}
+		})
+	}
+}
+
+func TestFakeInputs(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  options.Node
+		input *gotensor.Dense
+		ok    bool
+	}{
+		{"flattened", options.Node{Key: "data", Shape: []int{2, 3}}, gotensor.New(gotensor.WithShape(6), gotensor.WithBacking(make([]float32, 6))), true},
+		{"float16", options.Node{Key: "data", Shape: []int{2}}, gotensor.New(gotensor.Of(utils.Float16Dtype), gotensor.WithShape(2), gotensor.WithBacking(make([]utils.Float16, 2))), true},
+		{"no shape", options.Node{Key: "data"}, gotensor.New(gotensor.WithShape(5), gotensor.WithBacking(make([]uint8, 5))), true},
+		{"node dtype", options.Node{Key: "data", Shape: []int{2}, Dtype: gotensor.Float32}, gotensor.New(gotensor.WithShape(2), gotensor.WithBacking(make([]int32, 2))), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewFake(context.Background(), options.InputNodes([]options.Node{tc.node}))
+			if err != nil {
+				t.Fatalf("NewFake: %v", err)
+			}
+			err = f.Predict(context.Background(), []*gotensor.Dense{tc.input})
+			if tc.ok && err != nil {
+				t.Errorf("Predict: %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Error("Predict should fail")
+			}
+		})
+	}
+}
+
+// run with -race
+func TestFakeConcurrent(t *testing.T) {
+	f := newTestFake(t)
+	var wg sync.WaitGroup
+	for ii := 0; ii < 8; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.Predict(context.Background(), testInput()); err != nil {
+				t.Errorf("Predict: %v", err)
+				return
+			}
+			if _, err := f.ReadPredictionOutputs(context.Background()); err != nil {
+				t.Errorf("ReadPredictionOutputs: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestFakeClose(t *testing.T) {
+	f := newTestFake(t)
+	if err := f.Predict(context.Background(), testInput()); err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := f.Predict(context.Background(), testInput()); err == nil {
+		t.Error("Predict should fail once closed")
+	}
+	if _, err := f.ReadPredictionOutputs(context.Background()); err == nil {
+		t.Error("ReadPredictionOutputs should fail once closed")
+	}
+}
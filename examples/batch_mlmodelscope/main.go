@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"image"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -95,14 +94,11 @@ func main() {
 	}
 
 	// load model
-	symbol, err := ioutil.ReadFile(graph)
-	if err != nil {
-		panic(err)
-	}
-	params, err := ioutil.ReadFile(weights)
+	model, err := mxnet.LoadModelFiles(graph, weights)
 	if err != nil {
 		panic(err)
 	}
+	defer model.Close()
 
 	height := shape[2]
 	width := shape[3]
@@ -138,7 +134,7 @@ func main() {
 	}
 	input := []*gotensor.Dense{gotensor.New(
 		gotensor.Of(gotensor.Float32),
-		gotensor.WithShape(batchSize, height, width, channels),
+		gotensor.WithShape(batchSize, channels, height, width),
 		gotensor.WithBacking(dupImgFloats),
 	),
 	}
@@ -161,8 +157,8 @@ func main() {
 		ctx,
 		options.WithOptions(options.New()),
 		options.Device(device, 0),
-		options.Graph(symbol),
-		options.Weights(params),
+		options.Graph(model.Symbol),
+		options.Weights(model.Params),
 		options.BatchSize(batchSize),
 		options.InputNodes([]options.Node{in}),
 		options.OutputNodes([]options.Node{
@@ -12,7 +12,6 @@ import (
 	"context"
 	"fmt"
 	"image"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -95,14 +94,11 @@ func main() {
 	}
 
 	// load model
-	symbol, err := ioutil.ReadFile(graph)
-	if err != nil {
-		panic(err)
-	}
-	params, err := ioutil.ReadFile(weights)
+	model, err := mxnet.LoadModelFiles(graph, weights)
 	if err != nil {
 		panic(err)
 	}
+	defer model.Close()
 
 
 	height := shape[2]
@@ -158,8 +154,8 @@ func main() {
 		ctx,
 		options.WithOptions(opts),
 		options.Device(device, 0),
-		options.Graph(symbol),
-		options.Weights(params),
+		options.Graph(model.Symbol),
+		options.Weights(model.Params),
 		options.BatchSize(batchSize),
 		options.InputNodes([]options.Node{in}),
 		options.OutputNodes([]options.Node{
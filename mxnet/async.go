@@ -0,0 +1,77 @@
+package mxnet
+
+import (
+	"context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// Prediction is the result of PredictAsync
+type Prediction struct {
+	done    chan struct{}
+	outputs []gotensor.Tensor
+	err     error
+}
+
+// PredictAsync validates the inputs, and runs the prediction in the background.
+// Like Predict, the inputs can hold any number of samples.
+// The inputs must not be modified until the prediction completes.
+// Predictions on the same predictor are run one at a time: only one forward pass is
+// in flight per handle, and the outputs are read before the next prediction starts,
+// so each Prediction has the outputs of its own inputs.
+// If ctx is done before the forward pass starts, including while waiting for the
+// predictions before it, the prediction fails with ctx.Err().
+func (p *Predictor) PredictAsync(ctx context.Context, data []*gotensor.Dense) *Prediction {
+	pred := &Prediction{done: make(chan struct{})}
+
+	samples, batchSize, err := validateBatch(p.options.InputNodes(), data)
+	if err != nil {
+		pred.err = err
+		close(pred.done)
+		return pred
+	}
+
+	go func() {
+		defer close(pred.done)
+
+		span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_predict_async",
+			opentracing.Tags{
+				"evaluation_trace_level": p.GetOptions().TraceLevel(),
+			})
+		defer span.Finish()
+
+		if err := p.lockContext(ctx); err != nil {
+			pred.err = err
+			return
+		}
+		defer p.mu.Unlock()
+
+		if err := p.predict(ctx, data, samples, batchSize); err != nil {
+			pred.err = err
+			return
+		}
+		pred.outputs, pred.err = p.readPredictionOutputs(ctx)
+	}()
+
+	return pred
+}
+
+// Done is closed when the prediction completes
+func (f *Prediction) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the prediction completes and returns its error
+func (f *Prediction) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// Outputs blocks until the prediction completes and returns its outputs,
+// in the order of the output nodes
+func (f *Prediction) Outputs() ([]gotensor.Tensor, error) {
+	<-f.done
+	return f.outputs, f.err
+}
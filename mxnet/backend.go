@@ -0,0 +1,20 @@
+package mxnet
+
+import (
+	"context"
+
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/go-mxnet/backend"
+)
+
+var _ backend.Backend = (*Predictor)(nil)
+
+// NewBackend creates a Predictor and returns it as a backend.Backend.
+// It can be used wherever a backend.Constructor is expected.
+func NewBackend(ctx context.Context, opts ...options.Option) (backend.Backend, error) {
+	pred, err := New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return pred, nil
+}
@@ -0,0 +1,280 @@
+package mxnet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// BatcherOptions configures how a Batcher groups requests
+type BatcherOptions struct {
+	// MaxBatchSize is the most samples run in one forward pass.
+	// It defaults to, and cannot exceed, the batch size the predictor is bound to.
+	MaxBatchSize int
+	// MaxDelay is the longest the first sample of a batch waits for more samples.
+	// It defaults to 5ms.
+	MaxDelay time.Duration
+}
+
+// Batcher collects single sample Predict calls into batches for a predictor.
+// A batch runs once it has MaxBatchSize samples, or MaxDelay after its first sample arrived.
+// The samples are stacked along the leading (batch) dimension of the input nodes, batches
+// with fewer samples than the bound batch size are zero padded, and the outputs are split
+// back to each caller.
+// The predictor must not be used directly while the batcher is running.
+type Batcher struct {
+	predictor    *Predictor
+	batchSize    int
+	maxBatchSize int
+	maxDelay     time.Duration
+	requests     chan *batchRequest
+	done         chan struct{}
+	closeOnce    sync.Once
+	wg           sync.WaitGroup
+}
+
+type batchRequest struct {
+	ctx    context.Context
+	inputs []*gotensor.Dense
+	result chan batchResult
+}
+
+type batchResult struct {
+	outputs []gotensor.Tensor
+	err     error
+}
+
+// NewBatcher starts a batcher in front of predictor
+func NewBatcher(predictor *Predictor, opts BatcherOptions) (*Batcher, error) {
+	nodes := predictor.GetOptions().InputNodes()
+	batchSize, err := boundBatchSize(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBatchSize := opts.MaxBatchSize
+	if maxBatchSize == 0 {
+		maxBatchSize = batchSize
+	}
+	if maxBatchSize < 0 || maxBatchSize > batchSize {
+		return nil, newError("", ErrInvalidOptions, "invalid max batch size %d, the predictor is bound to a batch size of %d",
+			maxBatchSize, batchSize)
+	}
+	maxDelay := opts.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 5 * time.Millisecond
+	}
+
+	b := &Batcher{
+		predictor:    predictor,
+		batchSize:    batchSize,
+		maxBatchSize: maxBatchSize,
+		maxDelay:     maxDelay,
+		requests:     make(chan *batchRequest),
+		done:         make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.run()
+	return b, nil
+}
+
+// boundBatchSize returns the leading dimension shared by the input nodes
+func boundBatchSize(nodes []options.Node) (int, error) {
+	if len(nodes) == 0 {
+		return 0, newError("", ErrInvalidOptions, "no input nodes found")
+	}
+	batchSize := 0
+	for _, nd := range nodes {
+		if len(nd.Shape) == 0 || nd.Shape[0] <= 0 {
+			return 0, newError("", ErrInvalidShape, "input %s has no batch dimension in shape %v", nd.Key, nd.Shape)
+		}
+		if batchSize != 0 && nd.Shape[0] != batchSize {
+			return 0, newError("", ErrInvalidShape, "input %s has batch size %d, expecting %d", nd.Key, nd.Shape[0], batchSize)
+		}
+		batchSize = nd.Shape[0]
+	}
+	return batchSize, nil
+}
+
+// Predict runs a single sample, one tensor per input node without the batch dimension,
+// as part of a batch and returns its outputs, with a leading batch dimension of 1.
+func (b *Batcher) Predict(ctx context.Context, inputs []*gotensor.Dense) ([]gotensor.Tensor, error) {
+	nodes := b.predictor.GetOptions().InputNodes()
+	if len(inputs) != len(nodes) {
+		return nil, inputError("", ErrInvalidInput, "got %d inputs, but the predictor has %d input nodes",
+			len(inputs), len(nodes))
+	}
+	for ii, nd := range nodes {
+		input := inputs[ii]
+		if input == nil {
+			return nil, inputError(nd.Key, ErrInvalidInput, "input data is nil")
+		}
+		if input.Dtype() != nodeDtype(nd) {
+			return nil, inputError(nd.Key, ErrUnsupportedDtype, "got dtype %v, expecting %v",
+				input.Dtype(), nodeDtype(nd))
+		}
+		if input.Size() != prod(nd.Shape[1:]) {
+			return nil, inputError(nd.Key, ErrInvalidShape, "got %d elements, expecting %d for a sample of shape %v",
+				input.Size(), prod(nd.Shape[1:]), nd.Shape[1:])
+		}
+	}
+
+	req := &batchRequest{
+		ctx:    ctx,
+		inputs: inputs,
+		result: make(chan batchResult, 1),
+	}
+	select {
+	case b.requests <- req:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-b.done:
+		return nil, newError("MXPredForward", ErrPredictorClosed, "batcher is closed")
+	}
+
+	select {
+	case res := <-req.result:
+		return res.outputs, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops the batcher once the batch being run completes.
+// It does not close the predictor.
+func (b *Batcher) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+	b.wg.Wait()
+	return nil
+}
+
+func (b *Batcher) run() {
+	defer b.wg.Done()
+	for {
+		select {
+		case req := <-b.requests:
+			batch := []*batchRequest{req}
+			timer := time.NewTimer(b.maxDelay)
+		collect:
+			for len(batch) < b.maxBatchSize {
+				select {
+				case req := <-b.requests:
+					batch = append(batch, req)
+				case <-timer.C:
+					break collect
+				}
+			}
+			timer.Stop()
+			b.runBatch(batch)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *Batcher) runBatch(batch []*batchRequest) {
+	// callers that gave up while the batch was collected are not run
+	live := batch[:0]
+	for _, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			req.result <- batchResult{err: err}
+			continue
+		}
+		live = append(live, req)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	outputs, err := b.predictBatch(live)
+	for ii, req := range live {
+		if err != nil {
+			req.result <- batchResult{err: err}
+			continue
+		}
+		req.result <- batchResult{outputs: outputs[ii]}
+	}
+}
+
+// predictBatch runs the batch and returns the outputs of each request.
+// Each request gets a batcher_predict span from its context, and the forward pass
+// is traced under the span of the first request. The forward pass is not canceled
+// by the contexts of the requests, since it serves all of them.
+func (b *Batcher) predictBatch(batch []*batchRequest) ([][]gotensor.Tensor, error) {
+	var ctx context.Context
+	for jj, req := range batch {
+		span, spanCtx := tracer.StartSpanFromContext(req.ctx, tracer.MODEL_TRACE, "batcher_predict",
+			opentracing.Tags{
+				"batch_samples": len(batch),
+				"batch_index":   jj,
+			})
+		defer span.Finish()
+		if jj == 0 {
+			ctx = valuesContext{spanCtx}
+		}
+	}
+
+	nodes := b.predictor.GetOptions().InputNodes()
+	inputs := make([]*gotensor.Dense, len(nodes))
+	for ii, nd := range nodes {
+		samples := make([]*gotensor.Dense, len(batch))
+		for jj, req := range batch {
+			samples[jj] = req.inputs[ii]
+		}
+		input, err := stackBatch(nodeDtype(nd), nd.Shape, samples)
+		if err != nil {
+			return nil, err
+		}
+		inputs[ii] = input
+	}
+
+	if err := b.predictor.Predict(ctx, inputs); err != nil {
+		return nil, err
+	}
+	outputs, err := b.predictor.ReadPredictionOutputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([][]gotensor.Tensor, len(batch))
+	for jj := range batch {
+		res[jj] = make([]gotensor.Tensor, len(outputs))
+	}
+	for ii, output := range outputs {
+		dense, ok := output.(*gotensor.Dense)
+		if !ok {
+			return nil, newError("MXPredGetOutput", ErrInvalidShape, "output %d is not a dense tensor", ii)
+		}
+		shape := dense.Shape()
+		if len(shape) == 0 || shape[0] != b.batchSize {
+			return nil, newError("MXPredGetOutput", ErrInvalidShape,
+				"output %d has shape %v, without a leading batch dimension of %d it cannot be split", ii, shape, b.batchSize)
+		}
+		for jj := range batch {
+			sample, err := sliceBatch(dense, jj, jj+1)
+			if err != nil {
+				return nil, fmt.Errorf("cannot split output %d: %w", ii, err)
+			}
+			res[jj][ii] = sample
+		}
+	}
+	return res, nil
+}
+
+// valuesContext keeps the values of a context, such as its span, without its
+// deadline and cancelation
+type valuesContext struct {
+	context.Context
+}
+
+func (valuesContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesContext) Done() <-chan struct{}       { return nil }
+func (valuesContext) Err() error                  { return nil }
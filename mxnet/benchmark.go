@@ -0,0 +1,229 @@
+package mxnet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// the latencies are recorded in nanoseconds, with 3 significant figures, up to maxLatency
+const maxLatency = time.Minute
+
+// BenchmarkOptions configures Benchmark
+type BenchmarkOptions struct {
+	// number of untimed iterations run before the benchmark, see Predictor.Warmup
+	Warmup int
+	// number of timed iterations, defaults to 100
+	Iterations int
+	// number of goroutines running the iterations, defaults to 1.
+	// The forward passes of a predictor run one at a time, so concurrency
+	// measures the latency seen by concurrent callers, including the wait for the predictor.
+	Concurrency int
+}
+
+// LatencySummary is the latency distribution of one phase of the prediction
+type LatencySummary struct {
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+	Mean time.Duration `json:"mean"`
+}
+
+// BenchmarkResult is returned by Benchmark, durations are serialized in nanoseconds
+type BenchmarkResult struct {
+	Iterations  int           `json:"iterations"`
+	Concurrency int           `json:"concurrency"`
+	BatchSize   int           `json:"batch_size"`
+	Duration    time.Duration `json:"duration"`
+	// iterations per second
+	Throughput float64 `json:"throughput"`
+	// samples per second, the throughput times the batch size
+	SampleThroughput float64 `json:"sample_throughput"`
+
+	Total      LatencySummary `json:"total"`
+	SetInput   LatencySummary `json:"set_input"`
+	Forward    LatencySummary `json:"forward"`
+	OutputCopy LatencySummary `json:"output_copy"`
+}
+
+// Benchmark runs the predictor on inputs and measures the latency of each phase
+// of the prediction: setting the inputs, the forward pass and copying the outputs.
+// Zero inputs of the bound input shapes are used if inputs is nil.
+func Benchmark(ctx context.Context, p *Predictor, inputs []*gotensor.Dense, opts BenchmarkOptions) (*BenchmarkResult, error) {
+	if opts.Iterations == 0 {
+		opts.Iterations = 100
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Iterations < 0 || opts.Concurrency < 0 || opts.Warmup < 0 {
+		return nil, newError("", ErrInvalidOptions, "invalid benchmark options %+v", opts)
+	}
+
+	if inputs == nil {
+		inputs = p.zeroInputs()
+	}
+	if err := validateInputs(p.GetOptions().InputNodes(), inputs); err != nil {
+		return nil, err
+	}
+
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_benchmark",
+		opentracing.Tags{
+			"iterations":  opts.Iterations,
+			"concurrency": opts.Concurrency,
+		})
+	defer span.Finish()
+
+	if opts.Warmup > 0 {
+		if _, err := p.Warmup(ctx, opts.Warmup); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := newLatencyRecorder()
+	iterations := make(chan struct{}, opts.Iterations)
+	for ii := 0; ii < opts.Iterations; ii++ {
+		iterations <- struct{}{}
+	}
+	close(iterations)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	start := time.Now()
+	for ii := 0; ii < opts.Concurrency; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				if err := ctx.Err(); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					return
+				}
+				if err := p.benchmarkIteration(ctx, inputs, rec); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	batchSize := 1
+	if nodes := p.GetOptions().InputNodes(); len(nodes) != 0 && len(nodes[0].Shape) != 0 {
+		batchSize = nodes[0].Shape[0]
+	}
+	throughput := float64(opts.Iterations) / elapsed.Seconds()
+
+	return &BenchmarkResult{
+		Iterations:       opts.Iterations,
+		Concurrency:      opts.Concurrency,
+		BatchSize:        batchSize,
+		Duration:         elapsed,
+		Throughput:       throughput,
+		SampleThroughput: throughput * float64(batchSize),
+		Total:            summarize(rec.total),
+		SetInput:         summarize(rec.setInput),
+		Forward:          summarize(rec.forward),
+		OutputCopy:       summarize(rec.outputCopy),
+	}, nil
+}
+
+// benchmarkIteration runs one prediction, timing each phase under a single lock
+func (p *Predictor) benchmarkIteration(ctx context.Context, inputs []*gotensor.Dense, rec *latencyRecorder) error {
+	start := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	phase := time.Now()
+	for ii, nd := range p.options.InputNodes() {
+		if err := p.setInput(ctx, nd.Key, inputs[ii]); err != nil {
+			return err
+		}
+	}
+	setInput := time.Since(phase)
+
+	phase = time.Now()
+	if err := p.forward(ctx); err != nil {
+		return err
+	}
+	forward := time.Since(phase)
+
+	phase = time.Now()
+	if _, err := p.readPredictionOutputs(ctx); err != nil {
+		return err
+	}
+	outputCopy := time.Since(phase)
+
+	rec.record(time.Since(start), setInput, forward, outputCopy)
+	return nil
+}
+
+// latencyRecorder guards the histograms, which are not safe for concurrent use
+type latencyRecorder struct {
+	mu         sync.Mutex
+	total      *hdrhistogram.Histogram
+	setInput   *hdrhistogram.Histogram
+	forward    *hdrhistogram.Histogram
+	outputCopy *hdrhistogram.Histogram
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{
+		total:      newLatencyHistogram(),
+		setInput:   newLatencyHistogram(),
+		forward:    newLatencyHistogram(),
+		outputCopy: newLatencyHistogram(),
+	}
+}
+
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(1, int64(maxLatency), 3)
+}
+
+func (r *latencyRecorder) record(total, setInput, forward, outputCopy time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recordLatency(r.total, total)
+	recordLatency(r.setInput, setInput)
+	recordLatency(r.forward, forward)
+	recordLatency(r.outputCopy, outputCopy)
+}
+
+// recordLatency clamps d to the range of the histogram, RecordValue drops values out of range
+func recordLatency(h *hdrhistogram.Histogram, d time.Duration) {
+	v := int64(d)
+	if v < 1 {
+		v = 1
+	}
+	if v > int64(maxLatency) {
+		v = int64(maxLatency)
+	}
+	h.RecordValue(v)
+}
+
+func summarize(h *hdrhistogram.Histogram) LatencySummary {
+	if h.TotalCount() == 0 {
+		return LatencySummary{}
+	}
+	return LatencySummary{
+		P50:  time.Duration(h.ValueAtQuantile(50)),
+		P90:  time.Duration(h.ValueAtQuantile(90)),
+		P99:  time.Duration(h.ValueAtQuantile(99)),
+		Max:  time.Duration(h.Max()),
+		Mean: time.Duration(h.Mean()),
+	}
+}
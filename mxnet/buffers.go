@@ -0,0 +1,32 @@
+package mxnet
+
+import (
+	"sync"
+)
+
+// float32Buffers holds *[]float32 buffers for output reads
+var float32Buffers = sync.Pool{
+	New: func() interface{} {
+		buf := []float32{}
+		return &buf
+	},
+}
+
+// getFloat32Buffer returns a float32 slice of length size, reusing a pooled buffer if possible.
+// The content of the slice is undefined.
+func getFloat32Buffer(size int) []float32 {
+	buf := *float32Buffers.Get().(*[]float32)
+	if cap(buf) < size {
+		return make([]float32, size)
+	}
+	return buf[:size]
+}
+
+// putFloat32Buffer returns a buffer obtained with getFloat32Buffer to the pool
+func putFloat32Buffer(buf []float32) {
+	if cap(buf) == 0 {
+		return
+	}
+	buf = buf[:0]
+	float32Buffers.Put(&buf)
+}
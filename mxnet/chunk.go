@@ -0,0 +1,86 @@
+package mxnet
+
+import (
+	"context"
+
+	gotensor "gorgonia.org/tensor"
+)
+
+// predictChunks runs the samples of data in chunks of batchSize, zero padding the
+// last chunk, and keeps the outputs of the chunks concatenated and trimmed to the
+// number of samples. The reads of the outputs return them until the next forward pass.
+// The outputs must have the batch as their leading dimension.
+// It must be called with mu held.
+func (p *Predictor) predictChunks(ctx context.Context, data []*gotensor.Dense, samples, batchSize int) error {
+	nodes := p.options.InputNodes()
+
+	var pieces [][]*gotensor.Dense
+	for start := 0; start < samples; start += batchSize {
+		end := start + batchSize
+		if end > samples {
+			end = samples
+		}
+
+		chunk := make([]*gotensor.Dense, len(nodes))
+		for ii, nd := range nodes {
+			input, err := chunkBatch(data[ii], nd.Shape, start, end)
+			if err != nil {
+				return err
+			}
+			chunk[ii] = input
+		}
+		if err := p.predictBatch(ctx, chunk); err != nil {
+			return err
+		}
+
+		outputs, err := p.readPredictionOutputs(ctx)
+		if err != nil {
+			return err
+		}
+		if pieces == nil {
+			pieces = make([][]*gotensor.Dense, len(outputs))
+		}
+		for ii, output := range outputs {
+			dense, ok := output.(*gotensor.Dense)
+			if !ok {
+				return newError("MXPredGetOutput", ErrInvalidShape, "output %d is not a dense tensor", ii)
+			}
+			shape := dense.Shape()
+			if len(shape) == 0 || shape[0] != batchSize {
+				return newError("MXPredGetOutput", ErrInvalidShape, "output %d has shape %v, without a leading batch dimension of %d it cannot be chunked",
+					ii, shape, batchSize)
+			}
+			piece, err := sliceBatch(dense, 0, end-start)
+			if err != nil {
+				return err
+			}
+			pieces[ii] = append(pieces[ii], piece)
+		}
+	}
+
+	outputs := make([]*gotensor.Dense, len(pieces))
+	for ii, ps := range pieces {
+		shape := append([]int{samples}, ps[0].Shape()[1:]...)
+		output, err := stackBatch(ps[0].Dtype(), shape, ps)
+		if err != nil {
+			return err
+		}
+		outputs[ii] = output
+	}
+	p.outputs = outputs
+	return nil
+}
+
+// copyChunkedOutput copies an output kept by predictChunks into data, as getOutput does
+func copyChunkedOutput(output *gotensor.Dense, data []float32) error {
+	src, err := toFloat32s(output)
+	if err != nil {
+		return err
+	}
+	if len(src) != len(data) {
+		return newError("MXPredGetOutput", ErrInvalidShape, "the output has %d elements, but the destination has %d",
+			len(src), len(data))
+	}
+	copy(data, src)
+	return nil
+}
@@ -0,0 +1,172 @@
+package mxnet
+
+import (
+	"math"
+
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/go-mxnet/utils"
+	gotensor "gorgonia.org/tensor"
+)
+
+// the c predict api only exchanges float32 data with mxnet,
+// tensors of these dtypes are converted to and from float32 at the boundary
+var supportedDtypes = []gotensor.Dtype{
+	gotensor.Float32,
+	gotensor.Float64,
+	utils.Float16Dtype,
+	gotensor.Int32,
+	gotensor.Int8,
+	gotensor.Uint8,
+}
+
+func isSupportedDtype(dt gotensor.Dtype) bool {
+	for _, s := range supportedDtypes {
+		if s == dt {
+			return true
+		}
+	}
+	return false
+}
+
+// dtype of a node, nodes without a dtype are float32
+func nodeDtype(node options.Node) gotensor.Dtype {
+	if node.Dtype.Type == nil {
+		return gotensor.Float32
+	}
+	return node.Dtype
+}
+
+// convert the tensor data to float32
+// float32 tensors are returned without a copy, views are copied in element order first
+func toFloat32s(t *gotensor.Dense) ([]float32, error) {
+	switch data := materialize(t).Data().(type) {
+	case []float32:
+		return data, nil
+	case []float64:
+		res := make([]float32, len(data))
+		for ii, v := range data {
+			res[ii] = float32(v)
+		}
+		return res, nil
+	case []utils.Float16:
+		res := make([]float32, len(data))
+		for ii, v := range data {
+			res[ii] = v.Float32()
+		}
+		return res, nil
+	case []int32:
+		res := make([]float32, len(data))
+		for ii, v := range data {
+			res[ii] = float32(v)
+		}
+		return res, nil
+	case []int8:
+		res := make([]float32, len(data))
+		for ii, v := range data {
+			res[ii] = float32(v)
+		}
+		return res, nil
+	case []uint8:
+		res := make([]float32, len(data))
+		for ii, v := range data {
+			res[ii] = float32(v)
+		}
+		return res, nil
+	}
+	return nil, newError("MXPredSetInput", ErrUnsupportedDtype, "%v", t.Dtype())
+}
+
+// create a tensor of dtype dt from float32 data
+// float32 data is used as the tensor backing without a copy
+func fromFloat32s(dt gotensor.Dtype, shape []int, data []float32) (*gotensor.Dense, error) {
+	var backing interface{}
+	switch dt {
+	case gotensor.Float32:
+		backing = data
+	case gotensor.Float64:
+		res := make([]float64, len(data))
+		for ii, v := range data {
+			res[ii] = float64(v)
+		}
+		backing = res
+	case utils.Float16Dtype:
+		res := make([]utils.Float16, len(data))
+		for ii, v := range data {
+			res[ii] = utils.Float16FromFloat32(v)
+		}
+		backing = res
+	case gotensor.Int32:
+		res := make([]int32, len(data))
+		for ii, v := range data {
+			res[ii] = int32(roundClamp(v, math.MinInt32, math.MaxInt32))
+		}
+		backing = res
+	case gotensor.Int8:
+		res := make([]int8, len(data))
+		for ii, v := range data {
+			res[ii] = int8(roundClamp(v, math.MinInt8, math.MaxInt8))
+		}
+		backing = res
+	case gotensor.Uint8:
+		res := make([]uint8, len(data))
+		for ii, v := range data {
+			res[ii] = uint8(roundClamp(v, 0, math.MaxUint8))
+		}
+		backing = res
+	default:
+		return nil, newError("MXPredGetOutput", ErrUnsupportedDtype, "%v", dt)
+	}
+	return gotensor.New(
+		gotensor.Of(dt),
+		gotensor.WithShape(shape...),
+		gotensor.WithBacking(backing),
+	), nil
+}
+
+// copy float32 data into the backing of dst, converting it to the dtype of dst
+func copyFromFloat32s(dst *gotensor.Dense, src []float32) error {
+	switch data := dst.Data().(type) {
+	case []float32:
+		copy(data, src)
+	case []float64:
+		for ii := range data {
+			data[ii] = float64(src[ii])
+		}
+	case []utils.Float16:
+		for ii := range data {
+			data[ii] = utils.Float16FromFloat32(src[ii])
+		}
+	case []int32:
+		for ii := range data {
+			data[ii] = int32(roundClamp(src[ii], math.MinInt32, math.MaxInt32))
+		}
+	case []int8:
+		for ii := range data {
+			data[ii] = int8(roundClamp(src[ii], math.MinInt8, math.MaxInt8))
+		}
+	case []uint8:
+		for ii := range data {
+			data[ii] = uint8(roundClamp(src[ii], 0, math.MaxUint8))
+		}
+	default:
+		return newError("MXPredGetOutput", ErrUnsupportedDtype, "%v", dst.Dtype())
+	}
+	return nil
+}
+
+// round to the nearest integer and saturate to [lo, hi]
+// mxnet's quantized outputs are integral values stored as float32, so
+// this only guards against representation error and out of range values
+func roundClamp(v float32, lo, hi float64) float64 {
+	r := math.Round(float64(v))
+	if math.IsNaN(r) {
+		return 0
+	}
+	if r < lo {
+		return lo
+	}
+	if r > hi {
+		return hi
+	}
+	return r
+}
@@ -0,0 +1,65 @@
+package mxnet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rai-project/dlframework/framework/options"
+	gotensor "gorgonia.org/tensor"
+)
+
+// span slices the entries [start, end) of a dimension
+type span struct {
+	start, end int
+}
+
+func (s span) Start() int { return s.start }
+func (s span) End() int   { return s.end }
+func (s span) Step() int  { return 1 }
+
+// sliced returns the view of t sliced by slices
+func sliced(t *testing.T, src *gotensor.Dense, slices ...gotensor.Slice) *gotensor.Dense {
+	t.Helper()
+	view, err := src.Slice(slices...)
+	if err != nil {
+		t.Fatalf("Slice: %v", err)
+	}
+	return view.(*gotensor.Dense)
+}
+
+func TestToFloat32sView(t *testing.T) {
+	float32s := float32Tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6)
+	int32s := gotensor.New(gotensor.WithShape(2, 3), gotensor.WithBacking([]int32{1, 2, 3, 4, 5, 6}))
+
+	tests := []struct {
+		name  string
+		input *gotensor.Dense
+		want  []float32
+	}{
+		{"float32 rows", sliced(t, float32s, span{1, 2}), []float32{4, 5, 6}},
+		{"float32 columns", sliced(t, float32s, nil, span{1, 3}), []float32{2, 3, 5, 6}},
+		{"int32 columns", sliced(t, int32s, nil, span{0, 1}), []float32{1, 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := toFloat32s(tc.input)
+			if err != nil {
+				t.Fatalf("toFloat32s: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, expecting %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateInputsView(t *testing.T) {
+	nodes := []options.Node{{Key: "data", Shape: []int{2, 2}}}
+	src := float32Tensor([]int{2, 3}, 1, 2, 3, 4, 5, 6)
+	if err := validateInputs(nodes, []*gotensor.Dense{sliced(t, src, nil, span{1, 3})}); err != nil {
+		t.Errorf("validateInputs: %v", err)
+	}
+	if err := validateInputs(nodes, []*gotensor.Dense{sliced(t, src, nil, span{0, 1})}); err == nil {
+		t.Error("validateInputs should fail for a view of the wrong shape")
+	}
+}
@@ -0,0 +1,170 @@
+package mxnet
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Switch turns an engine feature on or off, or leaves it to the environment and the mxnet default
+type Switch int
+
+const (
+	SwitchDefault Switch = iota
+	SwitchOn
+	SwitchOff
+)
+
+// mxnet engine types, see MXNET_ENGINE_TYPE
+const (
+	NaiveEngine             = "NaiveEngine"
+	ThreadedEngine          = "ThreadedEngine"
+	ThreadedEnginePerDevice = "ThreadedEnginePerDevice"
+)
+
+// EngineConfig is the configuration of the mxnet engine.
+// mxnet reads it from the environment when the engine starts, so it must be applied
+// with ConfigureEngine before the first predictor is created.
+// The zero value leaves the environment as it is.
+// OMP_NUM_THREADS is not part of it: it is read when libgomp is loaded, before any Go
+// code runs, so it must be set in the environment of the process. OMPThreads caps the
+// OpenMP threads mxnet uses instead, through MXNET_OMP_MAX_THREADS.
+type EngineConfig struct {
+	BulkExecInference Switch // MXNET_EXEC_BULK_EXEC_INFERENCE
+	OperatorTuning    Switch // MXNET_USE_OPERATOR_TUNING
+	TensorRT          Switch // MXNET_USE_TENSORRT
+	TensorCore        Switch // MXNET_CUDA_ALLOW_TENSOR_CORE
+	CudnnAutotune     Switch // MXNET_CUDNN_AUTOTUNE_DEFAULT
+	EngineType        string // MXNET_ENGINE_TYPE, the predictor pool needs the NaiveEngine
+	OMPThreads        int    // MXNET_OMP_MAX_THREADS, 0 for the default
+	CPUWorkerThreads  int    // MXNET_CPU_WORKER_NTHREADS, 0 for the default
+}
+
+// engineEnv lists the environment variables read by the engine
+var engineEnv = []string{
+	"MXNET_EXEC_BULK_EXEC_INFERENCE",
+	"MXNET_USE_OPERATOR_TUNING",
+	"MXNET_USE_TENSORRT",
+	"MXNET_CUDA_ALLOW_TENSOR_CORE",
+	"MXNET_CUDNN_AUTOTUNE_DEFAULT",
+	"MXNET_ENGINE_TYPE",
+	"MXNET_OMP_MAX_THREADS",
+	"MXNET_CPU_WORKER_NTHREADS",
+}
+
+var engine struct {
+	mu      sync.Mutex
+	started bool
+	// the engine environment when the engine started
+	env map[string]string
+}
+
+// autoTuningDisabled is the configuration set by the DisableFrameworkAutoTuning option
+var autoTuningDisabled = EngineConfig{
+	BulkExecInference: SwitchOff,
+	OperatorTuning:    SwitchOff,
+	TensorRT:          SwitchOff,
+	TensorCore:        SwitchOff,
+	CudnnAutotune:     SwitchOff,
+}
+
+// environ returns the environment variables set by the configuration
+func (c EngineConfig) environ() map[string]string {
+	env := map[string]string{}
+	switches := []struct {
+		key string
+		sw  Switch
+	}{
+		{"MXNET_EXEC_BULK_EXEC_INFERENCE", c.BulkExecInference},
+		{"MXNET_USE_OPERATOR_TUNING", c.OperatorTuning},
+		{"MXNET_USE_TENSORRT", c.TensorRT},
+		{"MXNET_CUDA_ALLOW_TENSOR_CORE", c.TensorCore},
+		{"MXNET_CUDNN_AUTOTUNE_DEFAULT", c.CudnnAutotune},
+	}
+	for _, s := range switches {
+		switch s.sw {
+		case SwitchOn:
+			env[s.key] = "1"
+		case SwitchOff:
+			env[s.key] = "0"
+		}
+	}
+	if c.EngineType != "" {
+		env["MXNET_ENGINE_TYPE"] = c.EngineType
+	}
+	if c.OMPThreads > 0 {
+		env["MXNET_OMP_MAX_THREADS"] = strconv.Itoa(c.OMPThreads)
+	}
+	if c.CPUWorkerThreads > 0 {
+		env["MXNET_CPU_WORKER_NTHREADS"] = strconv.Itoa(c.CPUWorkerThreads)
+	}
+	return env
+}
+
+// ConfigureEngine sets the engine environment variables of c.
+// Once the engine started it fails with ErrEngineStarted, unless c does not change the
+// configuration the engine started with.
+func ConfigureEngine(c EngineConfig) error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	env := c.environ()
+	if engine.started {
+		var changed []string
+		for key, val := range env {
+			if engine.env[key] != val {
+				changed = append(changed, key)
+			}
+		}
+		if len(changed) != 0 {
+			sort.Strings(changed)
+			return newError("", ErrEngineStarted, "cannot change %s", strings.Join(changed, ", "))
+		}
+		return nil
+	}
+
+	for key, val := range env {
+		if err := os.Setenv(key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startedEngineEnv returns the value of the engine environment variable key when the
+// engine started, empty if the engine did not start
+func startedEngineEnv(key string) string {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	return engine.env[key]
+}
+
+// startEngine records the engine environment the first time it is called, and checks
+// that it did not change since on the following calls.
+// It is called before mxnet is used to create a predictor.
+func startEngine() error {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	if !engine.started {
+		engine.started = true
+		engine.env = map[string]string{}
+		for _, key := range engineEnv {
+			engine.env[key] = os.Getenv(key)
+		}
+		return nil
+	}
+
+	var changed []string
+	for _, key := range engineEnv {
+		if os.Getenv(key) != engine.env[key] {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) != 0 {
+		return newError("MXPredCreate", ErrEngineStarted, "%s changed after the engine started", strings.Join(changed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package mxnet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEngineConfigEnviron(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EngineConfig
+		env    map[string]string
+	}{
+		{
+			name:   "zero value",
+			config: EngineConfig{},
+			env:    map[string]string{},
+		},
+		{
+			name:   "auto tuning disabled",
+			config: autoTuningDisabled,
+			env: map[string]string{
+				"MXNET_EXEC_BULK_EXEC_INFERENCE": "0",
+				"MXNET_USE_OPERATOR_TUNING":      "0",
+				"MXNET_USE_TENSORRT":             "0",
+				"MXNET_CUDA_ALLOW_TENSOR_CORE":   "0",
+				"MXNET_CUDNN_AUTOTUNE_DEFAULT":   "0",
+			},
+		},
+		{
+			name: "threads",
+			config: EngineConfig{
+				BulkExecInference: SwitchOn,
+				EngineType:        NaiveEngine,
+				OMPThreads:        4,
+				CPUWorkerThreads:  2,
+			},
+			env: map[string]string{
+				"MXNET_EXEC_BULK_EXEC_INFERENCE": "1",
+				"MXNET_ENGINE_TYPE":              NaiveEngine,
+				"MXNET_OMP_MAX_THREADS":          "4",
+				"MXNET_CPU_WORKER_NTHREADS":      "2",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			env := tc.config.environ()
+			if !reflect.DeepEqual(env, tc.env) {
+				t.Errorf("got environment %v, expecting %v", env, tc.env)
+			}
+			for key := range env {
+				found := false
+				for _, k := range engineEnv {
+					found = found || k == key
+				}
+				if !found {
+					t.Errorf("%s is set but not checked by startEngine", key)
+				}
+			}
+		})
+	}
+}
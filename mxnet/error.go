@@ -6,14 +6,109 @@ package mxnet
 */
 import "C"
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 )
 
+// kinds of errors returned by the mxnet package, test for them with errors.Is.
+// ErrNative is a failure reported by libmxnet, the other kinds are caused by the caller.
+var (
+	ErrNative            = errors.New("mxnet native error")
+	ErrNoGPU             = errors.New("no GPU device")
+	ErrInvalidInput      = errors.New("invalid input data")
+	ErrInvalidShape      = errors.New("invalid shape")
+	ErrUnknownInput      = errors.New("unknown input")
+	ErrUnsupportedDtype  = errors.New("unsupported dtype")
+	ErrPredictorClosed   = errors.New("predictor is closed")
+	ErrProfileNotStarted = errors.New("mxnet profile was not started")
+	ErrEngineStarted     = errors.New("mxnet engine already started")
+	ErrFeatureMissing    = errors.New("feature missing from libmxnet")
+	ErrFeatureUnknown    = errors.New("feature support unknown")
+	ErrEngineType        = errors.New("unsupported mxnet engine type")
+	ErrInvalidOptions    = errors.New("invalid predictor options")
+	ErrUnknownOutput     = errors.New("unknown output")
+	ErrUnknownPredictor  = errors.New("predictor not from the pool")
+)
+
+// Error is an error of the mxnet package, get it with errors.As.
+// Func is the MXNet C API function that failed, or that the failed call was meant for.
+type Error struct {
+	Func string // e.g. MXPredCreate, empty if the error is not tied to a C API function
+	Kind error  // one of the Err* kinds
+	Msg  string // details, the MXGetLastError message for ErrNative
+}
+
+func (e *Error) Error() string {
+	msg := e.Msg
+	if e.Kind != ErrNative {
+		msg = e.Kind.Error()
+		if e.Msg != "" {
+			msg += " :: " + e.Msg
+		}
+	}
+	if e.Func == "" {
+		return "error in mxnet :: " + msg
+	}
+	return "error in mxnet :: " + e.Func + " :: " + msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func newError(fn string, kind error, format string, args ...interface{}) *Error {
+	return &Error{
+		Func: fn,
+		Kind: kind,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
 // get the last error happeneed.
 // go binding for MXGetLastError
 func GetLastError() error {
 	if err := C.MXGetLastError(); err != nil {
-		return errors.Errorf("error in mxnet :: %v", C.GoString(err))
+		return &Error{Kind: ErrNative, Msg: C.GoString(err)}
 	}
 	return nil
 }
+
+// lastError returns the last mxnet error, attributed to the C API function fn that failed
+func lastError(fn string) error {
+	err := &Error{Func: fn, Kind: ErrNative}
+	if msg := C.MXGetLastError(); msg != nil {
+		err.Msg = C.GoString(msg)
+	}
+	return err
+}
+
+// InputError is returned when the data passed to the predictor does not
+// match the input nodes it was created with
+type InputError struct {
+	Func   string // C API function the data was meant for
+	Key    string // name of the offending input node, empty if there is none
+	Kind   error  // ErrInvalidInput, ErrInvalidShape, ErrUnknownInput or ErrUnsupportedDtype
+	Reason string
+}
+
+func (e *InputError) Error() string {
+	msg := "error in mxnet :: " + e.Func + " :: "
+	if e.Key != "" {
+		msg += "input " + e.Key + " :: "
+	}
+	return msg + e.Kind.Error() + " :: " + e.Reason
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Kind
+}
+
+func inputError(key string, kind error, format string, args ...interface{}) *InputError {
+	return &InputError{
+		Func:   "MXPredSetInput",
+		Key:    key,
+		Kind:   kind,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,73 @@
+package mxnet
+
+import (
+	"runtime"
+	"sync"
+)
+
+// executor runs functions on a goroutine locked to one OS thread.
+// MXGetLastError is thread local, so every mxnet C call and the lastError that follows
+// a failure must run on the same thread. Each predictor has its own executor, and
+// the calls that are not tied to a predictor (profiler, ndarray lists, WaitAll) run
+// on the default executor.
+type executor struct {
+	calls  chan func()
+	mu     sync.RWMutex
+	closed bool
+}
+
+var (
+	defaultExecutorOnce sync.Once
+	defaultExec         *executor
+)
+
+// defaultExecutor is the executor for the calls that are not tied to a predictor
+func defaultExecutor() *executor {
+	defaultExecutorOnce.Do(func() {
+		defaultExec = newExecutor()
+	})
+	return defaultExec
+}
+
+func newExecutor() *executor {
+	e := &executor{
+		calls: make(chan func()),
+	}
+	go e.loop()
+	return e
+}
+
+func (e *executor) loop() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	for fn := range e.calls {
+		fn()
+	}
+}
+
+// do runs call on the executor thread and returns its error.
+// fn is the C API function of call, the error of a closed executor is attributed to it.
+// It fails with ErrPredictorClosed once the executor is closed.
+func (e *executor) do(fn string, call func() error) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return newError(fn, ErrPredictorClosed, "")
+	}
+	errc := make(chan error, 1)
+	e.calls <- func() {
+		errc <- call()
+	}
+	return <-errc
+}
+
+// close stops the executor thread once the pending calls have run
+func (e *executor) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	e.closed = true
+	close(e.calls)
+}
@@ -1,15 +1,22 @@
 package mxnet
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/Unknwon/com"
-	"github.com/pkg/errors"
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/go-mxnet/utils"
 	"gonum.org/v1/gonum/graph"
 	"gonum.org/v1/gonum/graph/simple"
 	"gonum.org/v1/gonum/graph/topo"
+	gotensor "gorgonia.org/tensor"
 )
 
 type GraphNode struct {
@@ -18,6 +25,8 @@ type GraphNode struct {
 	Name       string                 `json:"name"`
 	Inputs     [][]int64              `json:"inputs"`
 	Attributes map[string]interface{} `json:"param,omitempty"`
+	Attrs      map[string]string      `json:"attrs,omitempty"`
+	Attr       map[string]string      `json:"attr,omitempty"` // attrs before mxnet 0.9
 }
 
 type Graph struct {
@@ -31,19 +40,267 @@ type Graph struct {
 
 func NewGraph(symbolPath string) (*Graph, error) {
 	if !com.IsFile(symbolPath) {
-		return nil, errors.Errorf("file path %s not found", symbolPath)
+		return nil, fmt.Errorf("file path %s not found: %w", symbolPath, os.ErrNotExist)
 	}
 	bts, err := ioutil.ReadFile(symbolPath)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read %s", symbolPath)
+		return nil, fmt.Errorf("failed to read %s: %w", symbolPath, err)
 	}
+	g, err := NewGraphFromBytes(bts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmashal %s: %w", symbolPath, err)
+	}
+	return g, nil
+}
+
+func NewGraphFromBytes(symbol []byte) (*Graph, error) {
 	g := new(Graph)
-	if err := json.Unmarshal(bts, g); err != nil {
-		return nil, errors.Wrapf(err, "failed to unmashal %s", symbolPath)
+	// the symbol may be nul terminated for the c api
+	symbol = bytes.TrimRight(symbol, "\x00")
+	if err := json.Unmarshal(symbol, g); err != nil {
+		return nil, newError("", ErrInvalidOptions, "failed to unmashal symbol: %v", err)
 	}
 	return g, nil
 }
 
+// the output names of the operators that name their outputs (FListOutputNames in nnvm),
+// the outputs of the other operators are named output, or output<index> if there are several
+var listedOutputNames = map[string][]string{
+	"BatchNorm":              {"output", "mean", "var"},
+	"BatchNorm_v1":           {"output", "mean", "var"},
+	"CuDNNBatchNorm":         {"output", "mean", "var"},
+	"_contrib_SyncBatchNorm": {"output", "mean", "var"},
+	"LayerNorm":              {"output", "mean", "std"},
+	"RNN":                    {"output", "state", "state_cell"},
+}
+
+// OutputNames returns the names of the graph heads, as listed by mxnet.
+// The outputs of variables are named after the variable, and the outputs of an
+// operator are named <name>_output, or <name>_output<index> when the operator has
+// more than one output, unless the operator names its outputs (e.g. bn_mean for
+// BatchNorm). The number of outputs of a node comes from node_row_ptr; for symbols
+// saved without it, it is the number of outputs the graph uses.
+// Predictors list the names with MXSymbolListOutputs instead, see Predictor.OutputNames.
+func (g *Graph) OutputNames() ([]string, error) {
+	numOutputs, err := g.numOutputs()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(g.Heads))
+	for ii, head := range g.Heads {
+		nd := g.Nodes[head[0]]
+		index := 0
+		if len(head) > 1 {
+			index = head[1]
+		}
+		listed := listedOutputNames[nd.Op]
+		switch {
+		case nd.Op == "null":
+			names[ii] = nd.Name
+		case index < len(listed):
+			names[ii] = nd.Name + "_" + listed[index]
+		case numOutputs[head[0]] > 1:
+			names[ii] = fmt.Sprintf("%s_output%d", nd.Name, index)
+		default:
+			names[ii] = nd.Name + "_output"
+		}
+	}
+	return names, nil
+}
+
+// numOutputs returns the number of outputs of each node, from node_row_ptr, where
+// node i has the outputs [node_row_ptr[i], node_row_ptr[i+1]), or from the outputs
+// used by the other nodes and the heads when the graph has no node_row_ptr
+func (g *Graph) numOutputs() ([]int, error) {
+	for _, head := range g.Heads {
+		if len(head) == 0 || head[0] < 0 || head[0] >= len(g.Nodes) {
+			return nil, newError("", ErrInvalidOptions, "invalid graph head %v", head)
+		}
+	}
+
+	counts := make([]int, len(g.Nodes))
+	if len(g.NodeRowPtr) == len(g.Nodes)+1 {
+		for ii := range counts {
+			counts[ii] = g.NodeRowPtr[ii+1] - g.NodeRowPtr[ii]
+		}
+		return counts, nil
+	}
+
+	use := func(node, index int) {
+		if node >= 0 && node < len(counts) && index >= counts[node] {
+			counts[node] = index + 1
+		}
+	}
+	for _, nd := range g.Nodes {
+		for _, input := range nd.Inputs {
+			if len(input) > 1 {
+				use(int(input[0]), int(input[1]))
+			}
+		}
+	}
+	for _, head := range g.Heads {
+		index := 0
+		if len(head) > 1 {
+			index = head[1]
+		}
+		use(head[0], index)
+	}
+	return counts, nil
+}
+
+// Attribute returns the attribute of the node with the given key, such as __shape__
+func (nd GraphNode) Attribute(key string) (string, bool) {
+	if v, ok := nd.Attrs[key]; ok {
+		return v, true
+	}
+	if v, ok := nd.Attr[key]; ok {
+		return v, true
+	}
+	if v, ok := nd.Attributes[key]; ok {
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// InputNodes returns the inputs of the graph: the arguments that are not in params.
+// params holds the names of the parameters, with or without their arg:/aux: prefix.
+// The shape of an input comes from its __shape__ attribute, with an unknown leading
+// dimension replaced by batchSize, and its dtype from its __dtype__ attribute (float32 by default).
+// The label arguments of loss output operators (e.g. softmax_label for SoftmaxOutput)
+// without a __shape__ attribute are skipped, since they are not used for inference
+// and mxnet infers their shape; any other argument is an input, whatever its name.
+func (g *Graph) InputNodes(params []string, batchSize int) ([]options.Node, error) {
+	isParam := map[string]bool{}
+	for _, name := range params {
+		isParam[strings.TrimPrefix(strings.TrimPrefix(name, "arg:"), "aux:")] = true
+	}
+	isLabel := g.lossLabels()
+
+	nodes := []options.Node{}
+	for _, idx := range g.ArgNodes {
+		if idx < 0 || idx >= len(g.Nodes) {
+			return nil, newError("", ErrInvalidOptions, "invalid graph argument node %d", idx)
+		}
+		nd := g.Nodes[idx]
+		if isParam[nd.Name] {
+			continue
+		}
+		attr, ok := nd.Attribute("__shape__")
+		if !ok {
+			if isLabel[idx] {
+				continue
+			}
+			return nil, newError("", ErrInvalidShape, "input %s has no __shape__ attribute", nd.Name)
+		}
+		shape, err := parseShapeAttribute(attr)
+		if err != nil {
+			return nil, newError("", ErrInvalidShape, "invalid __shape__ attribute for input %s: %v", nd.Name, err)
+		}
+		if len(shape) != 0 && shape[0] == 0 {
+			shape[0] = batchSize
+		}
+		dtype, err := nodeDtypeAttribute(nd)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, options.Node{
+			Key:   nd.Name,
+			Shape: shape,
+			Dtype: dtype,
+		})
+	}
+	if len(nodes) == 0 {
+		return nil, newError("", ErrInvalidOptions, "no input nodes found in the graph")
+	}
+	return nodes, nil
+}
+
+// the loss output operators, whose second input is the label
+var lossOutputOps = map[string]bool{
+	"SoftmaxOutput":            true,
+	"Softmax":                  true,
+	"LinearRegressionOutput":   true,
+	"LogisticRegressionOutput": true,
+	"MAERegressionOutput":      true,
+	"SVMOutput":                true,
+}
+
+// lossLabels returns the indexes of the nodes that are the label input of a loss output operator
+func (g *Graph) lossLabels() map[int]bool {
+	labels := map[int]bool{}
+	for _, nd := range g.Nodes {
+		if lossOutputOps[nd.Op] && len(nd.Inputs) > 1 && len(nd.Inputs[1]) > 0 {
+			labels[int(nd.Inputs[1][0])] = true
+		}
+	}
+	return labels
+}
+
+// OutputNodes returns a node for each graph head. The nodes have no key, a keyed
+// output node requests a partial output, see New; the names of the heads are
+// returned by OutputNames.
+// The dtype of an output comes from the __dtype__ attribute of its node (float32 by default).
+func (g *Graph) OutputNodes() ([]options.Node, error) {
+	names, err := g.OutputNames()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]options.Node, len(names))
+	for ii, head := range g.Heads {
+		dtype, err := nodeDtypeAttribute(g.Nodes[head[0]])
+		if err != nil {
+			return nil, err
+		}
+		nodes[ii] = options.Node{
+			Dtype: dtype,
+		}
+	}
+	return nodes, nil
+}
+
+// parse a shape attribute such as (1, 3, 224, 224) or [1,3,224,224]
+func parseShapeAttribute(attr string) ([]int, error) {
+	attr = strings.Trim(strings.TrimSpace(attr), "()[]")
+	shape := []int{}
+	for _, dim := range strings.Split(attr, ",") {
+		dim = strings.TrimSpace(dim)
+		if dim == "" {
+			continue
+		}
+		d, err := strconv.Atoi(dim)
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			d = 0
+		}
+		shape = append(shape, d)
+	}
+	if len(shape) == 0 {
+		return nil, fmt.Errorf("empty shape %s", attr)
+	}
+	return shape, nil
+}
+
+func nodeDtypeAttribute(nd GraphNode) (gotensor.Dtype, error) {
+	attr, ok := nd.Attribute("__dtype__")
+	if !ok {
+		return gotensor.Float32, nil
+	}
+	flag, err := strconv.Atoi(strings.TrimSpace(attr))
+	if err != nil {
+		return gotensor.Dtype{}, newError("", ErrUnsupportedDtype, "invalid __dtype__ attribute for %s: %v", nd.Name, err)
+	}
+	dtype, err := utils.TypeFlag(flag).Dtype()
+	if err != nil {
+		return gotensor.Dtype{}, newError("", ErrUnsupportedDtype, "invalid __dtype__ attribute for %s: %v", nd.Name, err)
+	}
+	return dtype, nil
+}
+
 func (nd GraphNode) ID() int64 {
 	return nd.id
 }
@@ -79,7 +336,7 @@ func (g *Graph) TopologicallySortedNodes() ([]GraphNode, error) {
 
 	nds, err := topo.SortStabilized(grph, sortById)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to topologically sort graph")
+		return nil, fmt.Errorf("failed to topologically sort graph: %w", err)
 	}
 
 	res := []GraphNode{}
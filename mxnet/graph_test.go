@@ -0,0 +1,140 @@
+package mxnet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rai-project/dlframework/framework/options"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestGraphOutputNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		symbol string
+		names  []string
+	}{
+		{
+			name: "multi-output node with one head",
+			symbol: `{
+  "nodes": [
+    {"op": "null", "name": "data", "inputs": []},
+    {"op": "SliceChannel", "name": "split", "attrs": {"num_outputs": "2"}, "inputs": [[0, 0, 0]]}
+  ],
+  "arg_nodes": [0],
+  "node_row_ptr": [0, 1, 3],
+  "heads": [[1, 0, 0]]
+}`,
+			names: []string{"split_output0"},
+		},
+		{
+			name: "multi-output node without node_row_ptr",
+			symbol: `{
+  "nodes": [
+    {"op": "null", "name": "data", "inputs": []},
+    {"op": "SliceChannel", "name": "split", "attrs": {"num_outputs": "2"}, "inputs": [[0, 0, 0]]},
+    {"op": "Activation", "name": "relu", "attrs": {"act_type": "relu"}, "inputs": [[1, 1, 0]]}
+  ],
+  "arg_nodes": [0],
+  "heads": [[1, 0, 0], [2, 0, 0]]
+}`,
+			names: []string{"split_output0", "relu_output"},
+		},
+		{
+			name: "listed output names",
+			symbol: `{
+  "nodes": [
+    {"op": "null", "name": "data", "inputs": []},
+    {"op": "null", "name": "bn_gamma", "inputs": []},
+    {"op": "null", "name": "bn_beta", "inputs": []},
+    {"op": "null", "name": "bn_moving_mean", "inputs": []},
+    {"op": "null", "name": "bn_moving_var", "inputs": []},
+    {"op": "BatchNorm", "name": "bn", "inputs": [[0, 0, 0], [1, 0, 0], [2, 0, 0], [3, 0, 1], [4, 0, 1]]}
+  ],
+  "arg_nodes": [0, 1, 2, 3, 4],
+  "node_row_ptr": [0, 1, 2, 3, 4, 5, 8],
+  "heads": [[5, 0, 0], [5, 1, 0], [0, 0, 0]]
+}`,
+			names: []string{"bn_output", "bn_mean", "data"},
+		},
+		{
+			name: "single output",
+			symbol: `{
+  "nodes": [
+    {"op": "null", "name": "data", "inputs": []},
+    {"op": "Flatten", "name": "flatten", "inputs": [[0, 0, 0]]}
+  ],
+  "arg_nodes": [0],
+  "node_row_ptr": [0, 1, 2],
+  "heads": [[1, 0, 0]]
+}`,
+			names: []string{"flatten_output"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := NewGraphFromBytes([]byte(tc.symbol))
+			if err != nil {
+				t.Fatalf("NewGraphFromBytes: %v", err)
+			}
+			names, err := g.OutputNames()
+			if err != nil {
+				t.Fatalf("OutputNames: %v", err)
+			}
+			if !reflect.DeepEqual(names, tc.names) {
+				t.Errorf("got names %v, expecting %v", names, tc.names)
+			}
+
+			listed, err := symbolOutputNames([]byte(tc.symbol))
+			if err != nil {
+				t.Fatalf("symbolOutputNames: %v", err)
+			}
+			if !reflect.DeepEqual(listed, tc.names) {
+				t.Errorf("mxnet lists the names %v, expecting %v", listed, tc.names)
+			}
+		})
+	}
+
+	g := &Graph{Heads: [][]int{{1, 0, 0}}}
+	if _, err := g.OutputNames(); err == nil {
+		t.Error("OutputNames should fail for a head out of the graph")
+	}
+}
+
+func TestGraphInputNodes(t *testing.T) {
+	symbol := `{
+  "nodes": [
+    {"op": "null", "name": "data", "attrs": {"__shape__": "(0, 4)"}, "inputs": []},
+    {"op": "null", "name": "input_label", "attrs": {"__shape__": "(0, 1)", "__dtype__": "4"}, "inputs": []},
+    {"op": "Concat", "name": "concat", "attrs": {"num_args": "2"}, "inputs": [[0, 0, 0], [1, 0, 0]]},
+    {"op": "null", "name": "fc_weight", "inputs": []},
+    {"op": "null", "name": "fc_bias", "inputs": []},
+    {"op": "FullyConnected", "name": "fc", "attrs": {"num_hidden": "2"}, "inputs": [[2, 0, 0], [3, 0, 0], [4, 0, 0]]},
+    {"op": "null", "name": "softmax_label", "inputs": []},
+    {"op": "SoftmaxOutput", "name": "softmax", "inputs": [[5, 0, 0], [6, 0, 0]]}
+  ],
+  "arg_nodes": [0, 1, 3, 4, 6],
+  "heads": [[7, 0, 0]]
+}`
+	g, err := NewGraphFromBytes([]byte(symbol))
+	if err != nil {
+		t.Fatalf("NewGraphFromBytes: %v", err)
+	}
+	nodes, err := g.InputNodes([]string{"arg:fc_weight", "arg:fc_bias"}, 8)
+	if err != nil {
+		t.Fatalf("InputNodes: %v", err)
+	}
+	want := []options.Node{
+		{Key: "data", Shape: []int{8, 4}, Dtype: gotensor.Float32},
+		{Key: "input_label", Shape: []int{8, 1}, Dtype: gotensor.Int32},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("got input nodes %v, expecting %v", nodes, want)
+	}
+
+	// an input without a shape is not skipped because of its name
+	g.Nodes[1].Attrs = nil
+	if _, err := g.InputNodes([]string{"arg:fc_weight", "arg:fc_bias"}, 8); err == nil {
+		t.Error("InputNodes should fail for input_label without a __shape__ attribute")
+	}
+}
@@ -0,0 +1,61 @@
+package mxnet
+
+import (
+	"sync"
+	"time"
+)
+
+// LeakReport describes a predictor that was collected by the GC without being closed
+type LeakReport struct {
+	Stack       string    // stack trace of the goroutine that created the predictor
+	CollectedAt time.Time // when the finalizer freed the predictor
+}
+
+var leaks struct {
+	mu      sync.Mutex
+	enabled bool
+	reports []LeakReport
+}
+
+// SetLeakDetection turns the recording of leaked predictors on or off.
+// When it is on, the stack of every new predictor is recorded, and the predictors
+// that are collected by the GC without being closed are listed by LeakedPredictors.
+// It is meant for debugging, recording the stacks slows down the creation of predictors.
+func SetLeakDetection(enabled bool) {
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+	leaks.enabled = enabled
+}
+
+// LeakedPredictors returns the predictors collected without being closed since leak
+// detection was turned on, see SetLeakDetection
+func LeakedPredictors() []LeakReport {
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+	return append([]LeakReport(nil), leaks.reports...)
+}
+
+// ResetLeakedPredictors clears the list returned by LeakedPredictors
+func ResetLeakedPredictors() {
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+	leaks.reports = nil
+}
+
+func leakDetectionEnabled() bool {
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+	return leaks.enabled
+}
+
+func recordLeak(p *Predictor) {
+	if p.stack == nil {
+		return
+	}
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+	leaks.reports = append(leaks.reports, LeakReport{
+		Stack:       string(p.stack),
+		CollectedAt: time.Now(),
+	})
+}
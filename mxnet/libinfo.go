@@ -0,0 +1,98 @@
+package mxnet
+
+/*
+// go preamble
+typedef struct MXCallbackList MXCallbackList;
+#include <mxnet/c_api.h>
+*/
+import "C"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// LibInfo describes the libmxnet the package is linked with
+type LibInfo struct {
+	Version     string // e.g. 1.5.0
+	VersionCode int    // major*10000 + minor*100 + patch, as returned by MXGetVersion
+	// compile time features, e.g. CUDA, CUDNN, MKLDNN, OPENMP, PROFILER.
+	// MXLibInfoFeatures was added in MXNet 1.5, older versions only report CUDA and
+	// PROFILER, see readLibFeatures.
+	Features map[string]bool
+}
+
+// names of the features that are enabled, sorted
+func (l *LibInfo) EnabledFeatures() []string {
+	var names []string
+	for name, enabled := range l.Features {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// whether the feature is compiled in libmxnet, false if the features are unknown
+func (l *LibInfo) Enabled(feature string) bool {
+	return l.Features[feature]
+}
+
+var (
+	libInfoOnce sync.Once
+	libInfo     *LibInfo
+	libInfoErr  error
+)
+
+// get the version and the features of libmxnet, they are read once and cached
+// go binding for MXGetVersion and MXLibInfoFeatures, see LibInfo.Features
+func GetLibInfo() (*LibInfo, error) {
+	libInfoOnce.Do(func() {
+		libInfo, libInfoErr = readLibInfo()
+	})
+	return libInfo, libInfoErr
+}
+
+func readLibInfo() (*LibInfo, error) {
+	var version C.int
+	err := defaultExecutor().do("MXGetVersion", func() error {
+		if success := C.MXGetVersion(&version); success != 0 {
+			return lastError("MXGetVersion")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	code := int(version)
+	features, err := readLibFeatures(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LibInfo{
+		Version:     fmt.Sprintf("%d.%d.%d", code/10000, code/100%100, code%100),
+		VersionCode: code,
+		Features:    features,
+	}, nil
+}
+
+// requireFeature fails with ErrFeatureMissing if libmxnet reports that it was built
+// without feature, and with ErrFeatureUnknown if libmxnet does not report it.
+// fn is the C API function that needs the feature.
+func requireFeature(fn, feature string) error {
+	info, err := GetLibInfo()
+	if err != nil {
+		return err
+	}
+	enabled, known := info.Features[feature]
+	if !known {
+		return newError(fn, ErrFeatureUnknown, "libmxnet %s does not report whether it was built with %s", info.Version, feature)
+	}
+	if !enabled {
+		return newError(fn, ErrFeatureMissing, "libmxnet %s was built without %s", info.Version, feature)
+	}
+	return nil
+}
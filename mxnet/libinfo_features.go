@@ -0,0 +1,101 @@
+package mxnet
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdlib.h>
+
+// struct LibFeature of mxnet/libinfo.h, MXNet 1.5 and later
+typedef struct {
+	const char* name;
+	bool enabled;
+} lib_feature;
+
+// the address of the function name exported by the process, NULL if it is not
+static void* lookup_function(const char* name) {
+	void* self = dlopen(NULL, RTLD_LAZY);
+	if (self == NULL) {
+		return NULL;
+	}
+	void* fn = dlsym(self, name);
+	dlclose(self);
+	return fn;
+}
+
+static int call_lib_info_features(void* fn, const lib_feature** list, size_t* size) {
+	return ((int (*)(const lib_feature**, size_t*))fn)(list, size);
+}
+
+static int call_get_gpu_count(void* fn, int* count) {
+	return ((int (*)(int*))fn)(count);
+}
+*/
+import "C"
+import (
+	"unsafe"
+)
+
+// lookupFunction returns the address of the libmxnet C API function name, nil if
+// the libmxnet the package is linked with does not export it
+func lookupFunction(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.lookup_function(cname)
+}
+
+// readLibFeatures returns the compile time features of libmxnet
+// go binding for MXLibInfoFeatures, added in MXNet 1.5
+// MXLibInfoFeatures is looked up at runtime, so that older versions can be linked.
+// Their features come from the version instead: CUDA is reported when MXGetGPUCount
+// finds a GPU, it finds none without CUDA, and the profiler is built in since MXNet 1.2.
+// The other features are unknown.
+func readLibFeatures(versionCode int) (map[string]bool, error) {
+	if fn := lookupFunction("MXLibInfoFeatures"); fn != nil {
+		return listLibFeatures(fn)
+	}
+
+	features := map[string]bool{}
+	if versionCode >= 10200 {
+		features["PROFILER"] = true
+	}
+	if fn := lookupFunction("MXGetGPUCount"); fn != nil {
+		err := defaultExecutor().do("MXGetGPUCount", func() error {
+			var count C.int
+			features["CUDA"] = C.call_get_gpu_count(fn, &count) == 0 && count > 0
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return features, nil
+}
+
+// listLibFeatures calls MXLibInfoFeatures at fn
+func listLibFeatures(fn unsafe.Pointer) (map[string]bool, error) {
+	features := map[string]bool{}
+	err := defaultExecutor().do("MXLibInfoFeatures", func() error {
+		var (
+			list *C.lib_feature
+			size C.size_t
+		)
+		if success := C.call_lib_info_features(fn, &list, &size); success != 0 {
+			return lastError("MXLibInfoFeatures")
+		}
+		if size == 0 {
+			return nil
+		}
+		// c array to go, the array is static in libmxnet
+		items := (*[1 << 16]C.lib_feature)(unsafe.Pointer(list))[:size:size]
+		for _, item := range items {
+			features[C.GoString(item.name)] = bool(item.enabled)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return features, nil
+}
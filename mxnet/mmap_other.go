@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package mxnet
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mmapFile reads f, memory mapping is not supported on this platform
+func mmapFile(f *os.File) ([]byte, func() error, error) {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, nil, nil
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mxnet
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// mmapFile maps f read only
+func mmapFile(f *os.File) ([]byte, func() error, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, nil, errors.New("empty file")
+	}
+	if int64(int(size)) != size {
+		return nil, nil, errors.Errorf("file of %d bytes is too large to map", size)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
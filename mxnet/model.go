@@ -0,0 +1,204 @@
+package mxnet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rai-project/dlframework/framework/options"
+)
+
+// Model holds the symbol and the weights to create predictors from.
+// The weights of models loaded from files are memory mapped instead of read
+// into the Go heap, so the model must be closed once the predictors are created.
+type Model struct {
+	Symbol []byte // JSON symbol, nul terminated
+	Params []byte // raw bytes of the parameter ndarray file
+	unmap  func() error
+}
+
+// LoadModelDir loads the model in dir, made of a <prefix>-symbol.json file and a
+// <prefix>-<epoch>.params file. When there are several epochs, the latest is used,
+// params files without an epoch are only used when there is no other.
+func LoadModelDir(dir string) (*Model, error) {
+	symbols, err := filepath.Glob(filepath.Join(dir, "*-symbol.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(symbols) != 1 {
+		return nil, newError("", ErrInvalidOptions, "expecting one symbol file in %s, found %d", dir, len(symbols))
+	}
+	params, err := filepath.Glob(filepath.Join(dir, "*.params"))
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return nil, newError("", ErrInvalidOptions, "no params file found in %s", dir)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		ei, iok := paramsEpoch(params[i])
+		ej, jok := paramsEpoch(params[j])
+		if iok != jok {
+			return jok
+		}
+		if ei != ej {
+			return ei < ej
+		}
+		return params[i] < params[j]
+	})
+	return LoadModelFiles(symbols[0], params[len(params)-1])
+}
+
+// paramsEpoch returns the epoch of a <prefix>-<epoch>.params file, e.g. 10 for
+// model-0010.params, and false if the name has no epoch
+func paramsEpoch(path string) (int, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".params")
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return 0, false
+	}
+	epoch, err := strconv.Atoi(name[idx+1:])
+	if err != nil || epoch < 0 {
+		return 0, false
+	}
+	return epoch, true
+}
+
+// LoadModelFiles loads the model from a symbol file and a params file.
+// The params file is memory mapped.
+func LoadModelFiles(symbolPath, paramsPath string) (*Model, error) {
+	symbol, err := ioutil.ReadFile(symbolPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", symbolPath, err)
+	}
+	f, err := os.Open(paramsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", paramsPath, err)
+	}
+	defer f.Close()
+	return newMappedModel(symbol, f)
+}
+
+// LoadModelFS loads the model from a symbol file and a params file in fsys.
+// The params file is memory mapped when fsys is backed by the operating system
+// (e.g. os.DirFS), and read otherwise.
+func LoadModelFS(fsys fs.FS, symbolPath, paramsPath string) (*Model, error) {
+	symbol, err := fs.ReadFile(fsys, symbolPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", symbolPath, err)
+	}
+	f, err := fsys.Open(paramsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", paramsPath, err)
+	}
+	defer f.Close()
+	if osFile, ok := f.(*os.File); ok {
+		return newMappedModel(symbol, osFile)
+	}
+	params, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", paramsPath, err)
+	}
+	return newModel(symbol, params, nil)
+}
+
+// LoadModelReader loads the model from readers. The weights are read into memory.
+func LoadModelReader(symbol, params io.Reader) (*Model, error) {
+	sym, err := ioutil.ReadAll(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symbol: %w", err)
+	}
+	par, err := ioutil.ReadAll(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read params: %w", err)
+	}
+	return newModel(sym, par, nil)
+}
+
+// the first byte of the weights of each memory mapped model, see isMapped
+var mappedWeights sync.Map
+
+// isMapped returns whether weights are the memory mapped weights of a Model
+func isMapped(weights []byte) bool {
+	if len(weights) == 0 {
+		return false
+	}
+	_, ok := mappedWeights.Load(&weights[0])
+	return ok
+}
+
+func newMappedModel(symbol []byte, params *os.File) (*Model, error) {
+	data, unmap, err := mmapFile(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map %s: %w", params.Name(), err)
+	}
+	// the weights are read into memory where mapping is not supported
+	if unmap == nil || len(data) == 0 {
+		return newModel(symbol, data, unmap)
+	}
+	key := &data[0]
+	mappedWeights.Store(key, struct{}{})
+	return newModel(symbol, data, func() error {
+		mappedWeights.Delete(key)
+		return unmap()
+	})
+}
+
+func newModel(symbol, params []byte, unmap func() error) (*Model, error) {
+	if len(bytes.TrimSpace(bytes.TrimRight(symbol, "\x00"))) == 0 {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, newError("", ErrInvalidOptions, "invalid empty symbol")
+	}
+	if len(params) == 0 {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, newError("", ErrInvalidOptions, "invalid empty weights")
+	}
+	return &Model{
+		Symbol: nulTerminated(symbol),
+		Params: params,
+		unmap:  unmap,
+	}, nil
+}
+
+// Options returns the predictor options for the symbol and the weights of the model
+func (m *Model) Options() []options.Option {
+	return []options.Option{
+		options.Graph(m.Symbol),
+		options.Weights(m.Params),
+	}
+}
+
+// Close unmaps the weights. Predictors created from the model keep working, since
+// mxnet copies the weights when the predictor is created, and their options do not
+// keep mapped weights. The options returned by Options must not be used afterwards.
+func (m *Model) Close() error {
+	if m == nil || m.unmap == nil {
+		return nil
+	}
+	err := m.unmap()
+	m.unmap = nil
+	m.Params = nil
+	return err
+}
+
+// nulTerminated returns b with a trailing nul byte, so it can be passed as a char*
+func nulTerminated(b []byte) []byte {
+	if len(b) != 0 && b[len(b)-1] == 0 {
+		return b
+	}
+	res := make([]byte, len(b)+1)
+	copy(res, b)
+	return res
+}
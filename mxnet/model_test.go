@@ -0,0 +1,52 @@
+package mxnet
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadModelDirLatestEpoch(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"model-symbol.json":  `{"nodes": []}`,
+		"model-0009.params":  "epoch 9",
+		"model-0010.params":  "epoch 10",
+		"model-2.params":     "epoch 2",
+		"model-best.params":  "no epoch",
+		"model-00100.params": "epoch 100",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := LoadModelDir(dir)
+	if err != nil {
+		t.Fatalf("LoadModelDir: %v", err)
+	}
+	if got := string(m.Params); got != "epoch 100" {
+		t.Errorf("loaded the params %q, expecting the latest epoch", got)
+	}
+	params := m.Params
+	if mapped := runtime.GOOS == "linux" || runtime.GOOS == "darwin"; isMapped(params) != mapped {
+		t.Errorf("isMapped is %v for the params of a model loaded from a directory on %s", !mapped, runtime.GOOS)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if isMapped(params) {
+		t.Error("the params of a closed model should not be reported as memory mapped")
+	}
+
+	r, err := LoadModelReader(strings.NewReader(files["model-symbol.json"]), strings.NewReader("weights"))
+	if err != nil {
+		t.Fatalf("LoadModelReader: %v", err)
+	}
+	if isMapped(r.Params) {
+		t.Error("the params of a model read into memory should not be reported as memory mapped")
+	}
+}
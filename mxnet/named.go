@@ -0,0 +1,90 @@
+package mxnet
+
+import (
+	"context"
+
+	"github.com/rai-project/dlframework/framework/options"
+	gotensor "gorgonia.org/tensor"
+)
+
+// PredictNamed runs a forward pass with the input data keyed by input node name,
+// so the order of the input nodes in the options does not matter.
+func (p *Predictor) PredictNamed(ctx context.Context, data map[string]*gotensor.Dense) error {
+	nodes := p.options.InputNodes()
+	for key := range data {
+		if !hasInputNode(nodes, key) {
+			return inputError(key, ErrUnknownInput, "the predictor has no input node %s", key)
+		}
+	}
+
+	inputs := make([]*gotensor.Dense, len(nodes))
+	for ii, nd := range nodes {
+		input, ok := data[nd.Key]
+		if !ok {
+			return inputError(nd.Key, ErrInvalidInput, "missing input data")
+		}
+		inputs[ii] = input
+	}
+
+	return p.Predict(ctx, inputs)
+}
+
+// ReadNamedOutputs returns the outputs of the last forward pass keyed by output name.
+// The names are the requested layer names for partial outputs, and the names of
+// the graph heads otherwise.
+func (p *Predictor) ReadNamedOutputs(ctx context.Context) (map[string]gotensor.Tensor, error) {
+	names, err := p.OutputNames()
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := p.ReadPredictionOutputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]gotensor.Tensor, len(outputs))
+	for ii, output := range outputs {
+		res[names[ii]] = output
+	}
+	return res, nil
+}
+
+// OutputNames returns the name of each output of the predictor, in the order of
+// the output nodes
+func (p *Predictor) OutputNames() ([]string, error) {
+	outputNodes := p.options.OutputNodes()
+	keys, err := partialOutputKeys(outputNodes)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 0 {
+		return keys, nil
+	}
+
+	heads := p.outputNames
+	if len(outputNodes) > len(heads) {
+		return nil, newError("MXPredGetOutput", ErrInvalidOptions, "the predictor has %d output nodes, but the graph only has %d heads",
+			len(outputNodes), len(heads))
+	}
+
+	names := append([]string{}, heads[:len(outputNodes)]...)
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			return nil, newError("MXPredGetOutput", ErrInvalidOptions, "duplicate output name %s", name)
+		}
+		seen[name] = true
+	}
+	return names, nil
+}
+
+// listOutputNames returns the names of the outputs of a predictor created from opts: the
+// partial output keys when there are some, and the names of all the graph heads,
+// as listed by mxnet, otherwise. They are read once, when the predictor is created.
+func listOutputNames(opts *options.Options, outputKeys []string) ([]string, error) {
+	if len(outputKeys) != 0 {
+		return outputKeys, nil
+	}
+	return symbolOutputNames(opts.Graph())
+}
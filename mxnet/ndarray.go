@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"unsafe"
+
+	"github.com/rai-project/go-mxnet/params"
 )
 
 // NDArray List operator
@@ -46,18 +48,23 @@ func CreateNDListFromFile(filepath string) (*NDList, error) {
 		size   uint32         // go gc
 	)
 	// create ndarray list from raw bytes
-	success, err := C.MXNDListCreate((*C.char)(unsafe.Pointer(&b[0])),
-		C.int(len(b)),
-		&handle,
-		(*C.mx_uint)(unsafe.Pointer(&size)),
-	)
-
+	err = defaultExecutor().do("MXNDListCreate", func() error {
+		success, err := C.MXNDListCreate((*C.char)(unsafe.Pointer(&b[0])),
+			C.int(len(b)),
+			&handle,
+			(*C.mx_uint)(unsafe.Pointer(&size)),
+		)
+		if err != nil {
+			return err
+		}
+		if success < 0 {
+			return lastError("MXNDListCreate")
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if success < 0 {
-		return nil, GetLastError()
-	}
 	return &NDList{handle: handle, size: size}, nil
 }
 
@@ -78,18 +85,23 @@ func CreateNDListFromBytes(b []byte) (*NDList, error) {
 		size   uint32         // go gc
 	)
 	// create ndarray list from raw bytes
-	success, err := C.MXNDListCreate((*C.char)(unsafe.Pointer(&b[0])),
-		C.int(len(b)),
-		&handle,
-		(*C.mx_uint)(unsafe.Pointer(&size)),
-	)
-
+	err := defaultExecutor().do("MXNDListCreate", func() error {
+		success, err := C.MXNDListCreate((*C.char)(unsafe.Pointer(&b[0])),
+			C.int(len(b)),
+			&handle,
+			(*C.mx_uint)(unsafe.Pointer(&size)),
+		)
+		if err != nil {
+			return err
+		}
+		if success < 0 {
+			return lastError("MXNDListCreate")
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if success < 0 {
-		return nil, GetLastError()
-	}
 	return &NDList{handle: handle, size: size}, nil
 }
 
@@ -102,17 +114,23 @@ func (s *NDList) Get(index uint32) (*NDItem, error) {
 		shape *C.mx_uint  // pointer to ndarray shape
 		ndim  C.mx_uint   // number of dimension in the shape
 	)
-	success, err := C.MXNDListGet(s.handle,
-		C.mx_uint(index),
-		&key,
-		&data,
-		&shape,
-		&ndim,
-	)
+	err := defaultExecutor().do("MXNDListGet", func() error {
+		success, err := C.MXNDListGet(s.handle,
+			C.mx_uint(index),
+			&key,
+			&data,
+			&shape,
+			&ndim,
+		)
+		if err != nil {
+			return err
+		} else if success < 0 {
+			return lastError("MXNDListGet")
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
-	} else if success < 0 {
-		return nil, GetLastError()
 	}
 
 	size := uint32(1)
@@ -135,11 +153,19 @@ func (s *NDList) Get(index uint32) (*NDItem, error) {
 // free this NDList's C handle
 // go binding for MXNDListFree
 func (s *NDList) Free() error {
-	success, err := C.MXNDListFree(s.handle)
-	if err != nil {
-		return err
-	} else if success < 0 {
-		return GetLastError()
-	}
-	return nil
+	return defaultExecutor().do("MXNDListFree", func() error {
+		success, err := C.MXNDListFree(s.handle)
+		if err != nil {
+			return err
+		} else if success < 0 {
+			return lastError("MXNDListFree")
+		}
+		return nil
+	})
+}
+
+// names of the ndarrays stored in params, with their arg:/aux: prefix.
+// Only the name table is read, the arrays are not loaded.
+func paramNames(b []byte) ([]string, error) {
+	return params.Names(b)
 }
@@ -0,0 +1,254 @@
+package mxnet
+
+/*
+#include <mxnet/c_predict_api.h>
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"context"
+	"unsafe"
+
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// ReadPredictionOutputInto reads the output at index into dst, without allocating a new tensor.
+// dst must have the number of elements of the output, and the output shape if it has the same rank.
+// float32 outputs are copied by mxnet directly into the backing of dst, other dtypes are
+// converted through a pooled float32 buffer.
+// If dst is nil, the output is read into a new tensor, see ReadPooledOutputs to read
+// the outputs into pooled buffers instead.
+func (p *Predictor) ReadPredictionOutputInto(ctx context.Context, index int, dst *gotensor.Dense) (*gotensor.Dense, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readPredictionOutputInto(ctx, index, dst, nil)
+}
+
+// ReadPredictionOutputsInto reads every output into the matching tensor in dst,
+// see ReadPredictionOutputInto. dst may be nil, or have nil entries, to read into new tensors.
+func (p *Predictor) ReadPredictionOutputsInto(ctx context.Context, dst []*gotensor.Dense) ([]*gotensor.Dense, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_read_prediction_output_into")
+	defer span.Finish()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	outputNodes := p.options.OutputNodes()
+	if dst != nil && len(dst) != len(outputNodes) {
+		return nil, newError("MXPredGetOutput", ErrInvalidShape, "got %d output tensors, but the predictor has %d outputs",
+			len(dst), len(outputNodes))
+	}
+
+	res := make([]*gotensor.Dense, len(outputNodes))
+	for ii := range outputNodes {
+		var d *gotensor.Dense
+		if dst != nil {
+			d = dst[ii]
+		}
+		tensor, err := p.readPredictionOutputInto(ctx, ii, d, nil)
+		if err != nil {
+			return nil, err
+		}
+		res[ii] = tensor
+	}
+	return res, nil
+}
+
+// PooledOutputs holds outputs read into pooled buffers by ReadPooledOutputs
+type PooledOutputs struct {
+	// Tensors are the outputs in the order of the output nodes
+	Tensors []*gotensor.Dense
+	// the float32 buffers backing the tensors, owned by the package
+	buffers [][]float32
+}
+
+// Release hands the buffers backing the tensors back to the pool.
+// The tensors must not be used afterwards. Release can be called more than once.
+func (o *PooledOutputs) Release() {
+	for _, buf := range o.buffers {
+		putFloat32Buffer(buf)
+	}
+	o.buffers = nil
+	o.Tensors = nil
+}
+
+// ReadPooledOutputs reads every output into a tensor backed by a pooled buffer,
+// the buffers are reused once the outputs are released.
+// Only float32 outputs are backed by pooled buffers, other dtypes are converted into
+// new tensors.
+func (p *Predictor) ReadPooledOutputs(ctx context.Context) (*PooledOutputs, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_read_pooled_outputs")
+	defer span.Finish()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	outputNodes := p.options.OutputNodes()
+	res := &PooledOutputs{Tensors: make([]*gotensor.Dense, len(outputNodes))}
+	for ii := range outputNodes {
+		tensor, err := p.readPredictionOutputInto(ctx, ii, nil, res)
+		if err != nil {
+			res.Release()
+			return nil, err
+		}
+		res.Tensors[ii] = tensor
+	}
+	return res, nil
+}
+
+// ReadPredictionOutputToSlice copies the float32 output at index into dst, which must
+// have exactly the number of elements of the output, and returns the output shape
+// go binding for MXPredGetOutput
+func (p *Predictor) ReadPredictionOutputToSlice(ctx context.Context, index int, dst []float32) ([]int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkOutputIndex(index); err != nil {
+		return nil, err
+	}
+	shape, err := p.getOutputShape(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	if len(dst) != prod(shape) {
+		return nil, newError("MXPredGetOutput", ErrInvalidShape, "output %d has %d elements, but the destination has %d",
+			index, prod(shape), len(dst))
+	}
+	if err := p.getOutput(ctx, index, dst); err != nil {
+		return nil, err
+	}
+	return shape, nil
+}
+
+// readPredictionOutputInto reads the output at index into dst, or into a new tensor if
+// dst is nil. A new float32 tensor is backed by a pooled buffer recorded in pooled,
+// unless pooled is nil.
+func (p *Predictor) readPredictionOutputInto(ctx context.Context, index int, dst *gotensor.Dense, pooled *PooledOutputs) (*gotensor.Dense, error) {
+	if err := p.checkOutputIndex(index); err != nil {
+		return nil, err
+	}
+	dtype := nodeDtype(p.options.OutputNodes()[index])
+
+	shape, err := p.getOutputShape(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	size := prod(shape)
+
+	if dst == nil && dtype == gotensor.Float32 {
+		var buf []float32
+		if pooled != nil {
+			buf = getFloat32Buffer(size)
+		} else {
+			buf = make([]float32, size)
+		}
+		if err := p.getOutput(ctx, index, buf); err != nil {
+			if pooled != nil {
+				putFloat32Buffer(buf)
+			}
+			return nil, err
+		}
+		if pooled != nil {
+			pooled.buffers = append(pooled.buffers, buf)
+		}
+		return gotensor.New(
+			gotensor.Of(dtype),
+			gotensor.WithShape(shape...),
+			gotensor.WithBacking(buf),
+		), nil
+	}
+	if dst == nil {
+		buf := getFloat32Buffer(size)
+		defer putFloat32Buffer(buf)
+		if err := p.getOutput(ctx, index, buf); err != nil {
+			return nil, err
+		}
+		return fromFloat32s(dtype, shape, buf)
+	}
+
+	if err := checkOutputShape(index, shape, dst); err != nil {
+		return nil, err
+	}
+	if data, ok := dst.Data().([]float32); ok {
+		if err := p.getOutput(ctx, index, data[:size]); err != nil {
+			return nil, err
+		}
+		return dst, nil
+	}
+	if !isSupportedDtype(dst.Dtype()) {
+		return nil, newError("MXPredGetOutput", ErrUnsupportedDtype, "output %d cannot be read into a tensor of dtype %v",
+			index, dst.Dtype())
+	}
+	buf := getFloat32Buffer(size)
+	defer putFloat32Buffer(buf)
+	if err := p.getOutput(ctx, index, buf); err != nil {
+		return nil, err
+	}
+	if err := copyFromFloat32s(dst, buf); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+func (p *Predictor) checkOutputIndex(index int) error {
+	outputNodes := p.options.OutputNodes()
+	if index < 0 || index >= len(outputNodes) {
+		return newError("MXPredGetOutput", ErrUnknownOutput, "invalid output index %d, the predictor has %d outputs",
+			index, len(outputNodes))
+	}
+	return nil
+}
+
+// checkOutputShape checks that dst can hold the output at index
+func checkOutputShape(index int, shape []int, dst *gotensor.Dense) error {
+	if dst.Size() != prod(shape) {
+		return newError("MXPredGetOutput", ErrInvalidShape, "output %d has shape %v, but the destination has %d elements",
+			index, shape, dst.Size())
+	}
+	dstShape := dst.Shape()
+	if len(dstShape) != len(shape) {
+		return nil
+	}
+	for ii, dim := range dstShape {
+		if dim != shape[ii] {
+			return newError("MXPredGetOutput", ErrInvalidShape, "output %d has shape %v, but the destination has shape %v",
+				index, shape, dstShape)
+		}
+	}
+	return nil
+}
+
+// copy the output at index into data, which has the size of the output
+// go binding for MXPredGetOutput
+func (p *Predictor) getOutput(ctx context.Context, index int, data []float32) error {
+	if err := p.checkClosed("MXPredGetOutput"); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	span, recorded := p.startFrameworkSpan(ctx, "c_get_output")
+	defer span.Finish()
+	if recorded {
+		span.SetTag("output", index)
+		span.SetTag("elements", len(data))
+		span.SetTag("float_bytes", len(data)*4)
+	}
+	if p.outputs != nil {
+		return copyChunkedOutput(p.outputs[index], data)
+	}
+	return p.exec.do("MXPredGetOutput", func() error {
+		success := C.MXPredGetOutput(
+			p.handle,
+			C.mx_uint(index),
+			(*C.mx_float)(unsafe.Pointer(&data[0])),
+			C.mx_uint(len(data)),
+		)
+		if success != 0 {
+			return lastError("MXPredGetOutput")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,244 @@
+package mxnet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// PredictorPool hands out predictors that share one copy of the weights, when built
+// with the mxnet_multithread tag, to concurrent callers. Each predictor is used by one
+// caller at a time.
+type PredictorPool struct {
+	options    *options.Options
+	predictors []*Predictor
+	free       chan *Predictor
+	mu         sync.Mutex
+	inUse      map[*Predictor]bool // the predictors handed out by Get
+	open       int                 // the predictors not freed yet
+	freed      chan struct{}       // closed once every predictor is freed
+	err        error               // the first error freeing a predictor
+	stats      PoolStats
+	closed     bool
+}
+
+// PoolStats reports how long callers waited for a free predictor
+type PoolStats struct {
+	Requests  int64         `json:"requests"`
+	TotalWait time.Duration `json:"total_wait"`
+	MaxWait   time.Duration `json:"max_wait"`
+}
+
+// average time a caller waited for a free predictor
+func (s PoolStats) MeanWait() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Requests)
+}
+
+// Create a pool of size predictors sharing the same weights
+// go binding for MXPredCreateMultiThread
+// MXNet only supports more than one thread with the NaiveEngine, so the EngineType
+// must be set to NaiveEngine with ConfigureEngine before the first predictor is created.
+// Partial outputs are not supported by MXPredCreateMultiThread.
+// MXPredCreateMultiThread is only bound when building with the mxnet_multithread tag,
+// for libmxnet versions that have it. Without the tag, the pool holds size independent
+// predictors created with MXPredCreate, each with its own copy of the weights.
+func NewPredictorPool(ctx context.Context, size int, opts ...options.Option) (*PredictorPool, error) {
+	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_new_pool",
+		opentracing.Tags{
+			"pool_size": size,
+		})
+	defer span.Finish()
+
+	if size <= 0 {
+		return nil, newError("MXPredCreateMultiThread", ErrInvalidOptions, "invalid pool size %d", size)
+	}
+
+	options, outputKeys, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(outputKeys) != 0 {
+		return nil, newError("MXPredCreateMultiThread", ErrInvalidOptions,
+			"partial outputs are not supported by the predictor pool")
+	}
+	outputNames, err := listOutputNames(options, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol := nulTerminated(options.Graph())
+	params := options.Weights()
+	device := options.Devices()[0]
+	nodes := options.InputNodes()
+
+	inputKeys, shapeIdx, shapeData := inputShapes(nodes)
+	keys := cStringArray(inputKeys)
+	defer freeCStringArray(keys, len(inputKeys))
+
+	handles, err := createPoolHandles(symbol, params, int(device.Type()), int(device.ID()),
+		len(nodes), keys, shapeIdx, shapeData, size)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &PredictorPool{
+		predictors: make([]*Predictor, size),
+		free:       make(chan *Predictor, size),
+		inUse:      make(map[*Predictor]bool, size),
+		open:       size,
+		freed:      make(chan struct{}),
+	}
+	for ii, handle := range handles {
+		// each handle is meant for its own thread
+		pred := newPredictor(handle, options, outputNames, newExecutor())
+		pool.predictors[ii] = pred
+		pool.free <- pred
+	}
+	// the options of the predictors, without the weights if they are memory mapped
+	pool.options = pool.predictors[0].options
+
+	return pool, nil
+}
+
+func (pp *PredictorPool) GetOptions() *options.Options {
+	return pp.options
+}
+
+// number of predictors in the pool
+func (pp *PredictorPool) Size() int {
+	return len(pp.predictors)
+}
+
+// Get waits for a free predictor, or until ctx is done.
+// The predictor must be returned to the pool with Put.
+func (pp *PredictorPool) Get(ctx context.Context) (*Predictor, error) {
+	if pp.isClosed() {
+		return nil, newError("MXPredForward", ErrPredictorClosed, "predictor pool is closed")
+	}
+	start := time.Now()
+	select {
+	case pred := <-pp.free:
+		pp.recordWait(time.Since(start))
+		pp.mu.Lock()
+		defer pp.mu.Unlock()
+		if pp.closed {
+			pp.freePredictor(pred)
+			return nil, newError("MXPredForward", ErrPredictorClosed, "predictor pool is closed")
+		}
+		pp.inUse[pred] = true
+		return pred, nil
+	case <-ctx.Done():
+		pp.recordWait(time.Since(start))
+		return nil, ctx.Err()
+	}
+}
+
+// Put returns a predictor obtained with Get to the pool.
+// It fails with ErrUnknownPredictor for a predictor that is not in use, e.g. one
+// created with New or already returned. The predictor is freed if the pool is closed.
+func (pp *PredictorPool) Put(pred *Predictor) error {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	if !pp.inUse[pred] {
+		return newError("", ErrUnknownPredictor, "the predictor was not obtained from the pool with Get")
+	}
+	delete(pp.inUse, pred)
+	if pp.closed {
+		pp.freePredictor(pred)
+		return nil
+	}
+	pp.free <- pred
+	return nil
+}
+
+// freePredictor closes a predictor of the closed pool, pp.mu must be held
+func (pp *PredictorPool) freePredictor(pred *Predictor) {
+	if err := pred.Close(); err != nil && pp.err == nil {
+		pp.err = err
+	}
+	pp.open--
+	if pp.open == 0 {
+		close(pp.freed)
+	}
+}
+
+func (pp *PredictorPool) isClosed() bool {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return pp.closed
+}
+
+func (pp *PredictorPool) recordWait(wait time.Duration) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.stats.Requests++
+	pp.stats.TotalWait += wait
+	if wait > pp.stats.MaxWait {
+		pp.stats.MaxWait = wait
+	}
+}
+
+// Stats returns the queue wait time of the callers so far
+func (pp *PredictorPool) Stats() PoolStats {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return pp.stats
+}
+
+// Predict runs data on a free predictor and returns its outputs.
+// The time spent waiting for the predictor is recorded in the pool stats
+// and as the queue_wait tag of the pool_predict span.
+func (pp *PredictorPool) Predict(ctx context.Context, data []*gotensor.Dense) ([]gotensor.Tensor, error) {
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "pool_predict")
+	defer span.Finish()
+
+	start := time.Now()
+	pred, err := pp.Get(ctx)
+	span.SetTag("queue_wait", time.Since(start).String())
+	if err != nil {
+		return nil, err
+	}
+	defer pp.Put(pred)
+
+	if err := pred.Predict(ctx, data); err != nil {
+		return nil, err
+	}
+	return pred.ReadPredictionOutputs(ctx)
+}
+
+// Close frees the free predictors and waits for the predictors in use to be returned
+// with Put, which frees them, or until ctx is done. It returns ctx.Err() if the
+// predictors in use are not returned in time, they are still freed by Put afterwards.
+func (pp *PredictorPool) Close(ctx context.Context) error {
+	pp.mu.Lock()
+	if !pp.closed {
+		pp.closed = true
+	drain:
+		for {
+			select {
+			case pred := <-pp.free:
+				pp.freePredictor(pred)
+			default:
+				break drain
+			}
+		}
+	}
+	pp.mu.Unlock()
+
+	select {
+	case <-pp.freed:
+		pp.mu.Lock()
+		defer pp.mu.Unlock()
+		return pp.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,46 @@
+//go:build mxnet_multithread
+// +build mxnet_multithread
+
+package mxnet
+
+/*
+#include <mxnet/c_predict_api.h>
+#include <stdlib.h>
+*/
+import "C"
+import "unsafe"
+
+// createPoolHandles creates size predictor handles sharing the weights
+// go binding for MXPredCreateMultiThread
+// MXNet only supports more than one thread with the NaiveEngine.
+func createPoolHandles(symbol, params []byte, devType, devID, numInputs int, keys **C.char,
+	shapeIdx, shapeData []uint32, size int) ([]C.PredictorHandle, error) {
+	if engineType := startedEngineEnv("MXNET_ENGINE_TYPE"); engineType != NaiveEngine {
+		return nil, newError("MXPredCreateMultiThread", ErrEngineType,
+			"the predictor pool needs the %s, but the engine started as %q", NaiveEngine, engineType)
+	}
+	handles := make([]C.PredictorHandle, size)
+	err := defaultExecutor().do("MXPredCreateMultiThread", func() error {
+		success := C.MXPredCreateMultiThread(
+			(*C.char)(unsafe.Pointer(&symbol[0])),
+			unsafe.Pointer(&params[0]),
+			C.int(len(params)),
+			C.int(devType),
+			C.int(devID),
+			C.mx_uint(numInputs),
+			keys,
+			(*C.mx_uint)(unsafe.Pointer(&shapeIdx[0])),
+			(*C.mx_uint)(unsafe.Pointer(&shapeData[0])),
+			C.int(size),
+			&handles[0],
+		)
+		if success != 0 {
+			return lastError("MXPredCreateMultiThread")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return handles, nil
+}
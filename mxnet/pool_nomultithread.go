@@ -0,0 +1,49 @@
+//go:build !mxnet_multithread
+// +build !mxnet_multithread
+
+package mxnet
+
+/*
+#include <mxnet/c_predict_api.h>
+*/
+import "C"
+import "unsafe"
+
+// createPoolHandles creates size independent predictor handles with MXPredCreate,
+// MXPredCreateMultiThread is not bound without the mxnet_multithread tag.
+// Unlike the handles of MXPredCreateMultiThread, each handle holds its own copy of
+// the weights, and any engine type can be used.
+func createPoolHandles(symbol, params []byte, devType, devID, numInputs int, keys **C.char,
+	shapeIdx, shapeData []uint32, size int) ([]C.PredictorHandle, error) {
+	handles := make([]C.PredictorHandle, 0, size)
+	err := defaultExecutor().do("MXPredCreate", func() error {
+		for ii := 0; ii < size; ii++ {
+			var handle C.PredictorHandle
+			success := C.MXPredCreate(
+				(*C.char)(unsafe.Pointer(&symbol[0])),
+				unsafe.Pointer(&params[0]),
+				C.int(len(params)),
+				C.int(devType),
+				C.int(devID),
+				C.mx_uint(numInputs),
+				keys,
+				(*C.mx_uint)(unsafe.Pointer(&shapeIdx[0])),
+				(*C.mx_uint)(unsafe.Pointer(&shapeData[0])),
+				&handle,
+			)
+			if success != 0 {
+				err := lastError("MXPredCreate")
+				for _, h := range handles {
+					C.MXPredFree(h)
+				}
+				return err
+			}
+			handles = append(handles, handle)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return handles, nil
+}
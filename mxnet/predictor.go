@@ -7,25 +7,39 @@ package mxnet
 import "C"
 import (
 	"context"
+	"path/filepath"
 	"runtime"
-  "unsafe"
-  "path/filepath"
-  "strings"
-  
-  opentracing "github.com/opentracing/opentracing-go"
-	gotensor "gorgonia.org/tensor"
-	"github.com/pkg/errors"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"unsafe"
+
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/rai-project/dlframework/framework/options"
+	cupti "github.com/rai-project/go-cupti"
 	nvidiasmi "github.com/rai-project/nvidia-smi"
-  "github.com/rai-project/tracer"
-  cupti "github.com/rai-project/go-cupti"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
 )
 
 // predictor for inference
 type Predictor struct {
 	handle  C.PredictorHandle // C handle of predictor
-  options *options.Options
-  cu        *cupti.CUPTI
+	options *options.Options
+	cu      *cupti.CUPTI
+	// serializes the use of handle. Predict holds it until the forward pass
+	// completes, even when it returned early because its context was done
+	mu sync.Mutex
+	// runs the C calls of the predictor on one OS thread
+	exec *executor
+	// set by Close, guarded by mu
+	closed bool
+	// where the predictor was created, only recorded when leak detection is enabled
+	stack []byte
+	// the outputs of a chunked Predict, guarded by mu and reset by the next forward pass
+	outputs []*gotensor.Dense
+	// the names of the outputs, see OutputNames
+	outputNames []string
 }
 
 func prod(arry []int) int {
@@ -42,51 +56,252 @@ func prod(arry []int) int {
 // param params In-memory raw bytes of parameter ndarray file
 // param device Device to run predictor
 // param nodes An array of InputNode which stored the name and shape data of ndarray item
+// If the output nodes have keys, the predictor is created with
+// MXPredCreatePartialOut and outputs the named internal layers in the order of the
+// output nodes instead of the graph heads. A key is either the output name of the
+// layer (e.g. pool5_output) or the layer name (e.g. pool5), see partialOutputLayers.
+// Input and output nodes that are not set are discovered from the graph.
+// The options of the predictor, see GetOptions, do not hold the weights when they
+// are memory mapped by a Model.
 func New(ctx context.Context, opts ...options.Option) (*Predictor, error) {
 	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_new")
 	defer span.Finish()
 
-	var (
-		pc        *C.char
-		shapeIdx  = []uint32{0}
-		shapeData = []uint32{}
-	)
+	options, outputKeys, err := newOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	outputNames, err := listOutputNames(options, outputKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol := nulTerminated(options.Graph())
+	params := options.Weights()
+	device := options.Devices()[0]
+	nodes := options.InputNodes()
+
+	inputKeys, shapeIdx, shapeData := inputShapes(nodes)
+	keys := cStringArray(inputKeys)
+	defer freeCStringArray(keys, len(inputKeys))
+
+	var outKeys **C.char
+	if len(outputKeys) != 0 {
+		outKeys = cStringArray(partialOutputLayers(outputKeys))
+		defer freeCStringArray(outKeys, len(outputKeys))
+	}
+
+	var handle C.PredictorHandle
+
+	createSpan, _ := tracer.StartSpanFromContext(ctx, tracer.FRAMEWORK_TRACE, "c_pred_create",
+		opentracing.Tags{
+			"input_shapes":  inputNodeShapes(nodes),
+			"weights_bytes": len(params),
+			"device_type":   int(device.Type()),
+			"device_id":     device.ID(),
+			"batch_size":    options.BatchSize(),
+		})
+	exec := newExecutor()
+	err = exec.do("MXPredCreate", func() error {
+		if len(outputKeys) == 0 {
+			success := C.MXPredCreate(
+				(*C.char)(unsafe.Pointer(&symbol[0])),
+				unsafe.Pointer(&params[0]),
+				C.int(len(params)),
+				C.int(device.Type()),
+				C.int(device.ID()),
+				C.mx_uint(len(nodes)),
+				keys,
+				(*C.mx_uint)(unsafe.Pointer(&shapeIdx[0])),
+				(*C.mx_uint)(unsafe.Pointer(&shapeData[0])),
+				&handle,
+			)
+			if success != 0 {
+				return lastError("MXPredCreate")
+			}
+			return nil
+		}
+		success := C.MXPredCreatePartialOut(
+			(*C.char)(unsafe.Pointer(&symbol[0])),
+			unsafe.Pointer(&params[0]),
+			C.int(len(params)),
+			C.int(device.Type()),
+			C.int(device.ID()),
+			C.mx_uint(len(nodes)),
+			keys,
+			(*C.mx_uint)(unsafe.Pointer(&shapeIdx[0])),
+			(*C.mx_uint)(unsafe.Pointer(&shapeData[0])),
+			C.mx_uint(len(outputKeys)),
+			outKeys,
+			&handle,
+		)
+		if success != 0 {
+			return lastError("MXPredCreatePartialOut")
+		}
+		return nil
+	})
+	createSpan.Finish()
+	if err != nil {
+		exec.close()
+		return nil, err
+	}
+
+	return newPredictor(handle, options, outputNames, exec), nil
+}
+
+// inputNodeShapes maps the input node names to their shapes, for the span tags
+func inputNodeShapes(nodes []options.Node) map[string][]int {
+	shapes := make(map[string][]int, len(nodes))
+	for _, nd := range nodes {
+		shapes[nd.Key] = nd.Shape
+	}
+	return shapes
+}
+
+// newPredictor wraps a C handle whose calls run on exec, outputNames are the names
+// of its outputs, see listOutputNames.
+// The handle is freed by Close, or by the finalizer if the predictor is not closed.
+// The weights are dropped from the options when they are memory mapped by a Model,
+// which may be closed once the predictor is created: mxnet copied them into the handle.
+func newPredictor(handle C.PredictorHandle, opts *options.Options, outputNames []string, exec *executor) *Predictor {
+	if isMapped(opts.Weights()) {
+		opts = options.New(
+			options.WithOptions(opts),
+			options.Weights(nil),
+		)
+	}
+	pred := &Predictor{handle: handle, options: opts, exec: exec, outputNames: outputNames}
+	if leakDetectionEnabled() {
+		pred.stack = debug.Stack()
+	}
+
+	runtime.SetFinalizer(pred, (*Predictor).finalizer)
 
+	return pred
+}
+
+// newOptions creates the predictor options and checks that a predictor can be created from them.
+// It also returns the partial output keys requested by the caller, see partialOutputKeys.
+// Missing input or output nodes are discovered from the graph, see discoverNodes.
+func newOptions(opts ...options.Option) (*options.Options, []string, error) {
 	options := options.New(opts...)
 	if len(options.Graph()) == 0 {
-		return nil, errors.New("invalid empty symbol")
+		return nil, nil, newError("MXPredCreate", ErrInvalidOptions, "empty symbol")
 	}
 	if len(options.Weights()) == 0 {
-		return nil, errors.New("invalid empty weights")
-	}
-	if len(options.InputNodes()) == 0 {
-		return nil, errors.New("no input nodes found")
+		return nil, nil, newError("MXPredCreate", ErrInvalidOptions, "empty weights")
 	}
 	if len(options.Devices()) == 0 {
-		return nil, errors.New("no devices defined")
+		return nil, nil, newError("MXPredCreate", ErrInvalidOptions, "no devices defined")
 	}
 
 	if options.DisableFrameworkAutoTuning() {
-		disableFrameworkAutoTuning()
+		if err := ConfigureEngine(autoTuningDisabled); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	symbol := options.Graph()
-	params := options.Weights()
-	device := options.Devices()[0]
-	nodes := options.InputNodes()
-
 	if options.UsesGPU() && !nvidiasmi.HasGPU {
-		return nil, errors.New("no GPU device")
+		return nil, nil, newError("MXPredCreate", ErrNoGPU, "the options use a GPU device")
+	}
+	if options.UsesGPU() {
+		if err := requireFeature("MXPredCreate", "CUDA"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := startEngine(); err != nil {
+		return nil, nil, err
+	}
+
+	outputKeys, err := partialOutputKeys(options.OutputNodes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(options.InputNodes()) == 0 || len(options.OutputNodes()) == 0 {
+		options, err = discoverNodes(options)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return options, outputKeys, nil
+}
+
+// discoverNodes fills in the input and the output nodes that are not set from the graph.
+// The inputs are the graph arguments that are not in the weights, and the outputs are the graph heads.
+func discoverNodes(opts *options.Options) (*options.Options, error) {
+	graph, err := NewGraphFromBytes(opts.Graph())
+	if err != nil {
+		return nil, err
+	}
+
+	inputNodes := opts.InputNodes()
+	if len(inputNodes) == 0 {
+		params, err := paramNames(opts.Weights())
+		if err != nil {
+			return nil, err
+		}
+		inputNodes, err = graph.InputNodes(params, int(opts.BatchSize()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	outputNodes := opts.OutputNodes()
+	if len(outputNodes) == 0 {
+		outputNodes, err = graph.OutputNodes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options.New(
+		options.WithOptions(opts),
+		options.InputNodes(inputNodes),
+		options.OutputNodes(outputNodes),
+	), nil
+}
+
+// partialOutputKeys returns the names of the internal layers requested
+// through the output nodes, or nil if the graph heads should be used.
+// Either all output nodes or none of them must have a key.
+func partialOutputKeys(nodes []options.Node) ([]string, error) {
+	keys := []string{}
+	for _, nd := range nodes {
+		if nd.Key != "" {
+			keys = append(keys, nd.Key)
+		}
 	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if len(keys) != len(nodes) {
+		return nil, newError("MXPredCreatePartialOut", ErrInvalidOptions,
+			"either all or none of the output nodes must have a layer name")
+	}
+	return keys, nil
+}
 
-	// malloc a **char which like []string to store node keys
-	keys := C.malloc(C.size_t(len(nodes)) * C.size_t(unsafe.Sizeof(pc))) // c gc
+// partialOutputLayers returns the layer names of the partial output keys.
+// MXPredCreatePartialOut appends "_output" to each key before looking it up in the
+// internal outputs of the graph, so the suffix of keys such as pool5_output is removed.
+func partialOutputLayers(keys []string) []string {
+	layers := make([]string, len(keys))
+	for ii, key := range keys {
+		layers[ii] = strings.TrimSuffix(key, "_output")
+	}
+	return layers
+}
+
+// inputShapes flattens the input nodes into the key, shape index and
+// shape data arrays expected by the MXPred* functions
+func inputShapes(nodes []options.Node) (keys []string, shapeIdx []uint32, shapeData []uint32) {
+	shapeIdx = []uint32{0}
+	shapeData = []uint32{}
 	jj := 0
-	for ii, nd := range nodes {
-		// get memory address
-		p := (**C.char)(unsafe.Pointer(uintptr(keys) + uintptr(ii)*unsafe.Sizeof(pc)))
-		// c gc
-		*p = C.CString(nd.Key)
+	for _, nd := range nodes {
+		keys = append(keys, nd.Key)
 		shape := intSliceToUint32(nd.Shape)
 		// shapeIdx for next node
 		jj += len(shape)
@@ -94,99 +309,322 @@ func New(ctx context.Context, opts ...options.Option) (*Predictor, error) {
 		// shape data for current node
 		shapeData = append(shapeData, shape...)
 	}
+	return
+}
 
-	var handle C.PredictorHandle
-
-	err := C.MXPredCreate(
-		(*C.char)(unsafe.Pointer(&symbol[0])),
-		unsafe.Pointer(&params[0]),
-		C.int(len(params)),
-		C.int(device.Type()),
-		C.int(device.ID()),
-		C.mx_uint(len(nodes)),
-		(**C.char)(keys),
-		(*C.mx_uint)(unsafe.Pointer(&shapeIdx[0])),
-		(*C.mx_uint)(unsafe.Pointer(&shapeData[0])),
-		&handle,
-	)
+// malloc a **char which like []string to store the strings
+// the result must be released with freeCStringArray, go gc won't do that for us
+func cStringArray(strs []string) **C.char {
+	var pc *C.char
+	arry := C.malloc(C.size_t(len(strs)) * C.size_t(unsafe.Sizeof(pc))) // c gc
+	for ii, str := range strs {
+		// get memory address
+		p := (**C.char)(unsafe.Pointer(uintptr(arry) + uintptr(ii)*unsafe.Sizeof(pc)))
+		// c gc
+		*p = C.CString(str)
+	}
+	return (**C.char)(arry)
+}
 
-	// free mem we created before return, go gc won't do that for us
-	for ii := range nodes {
-		p := (**C.char)(unsafe.Pointer(uintptr(keys) + uintptr(ii)*unsafe.Sizeof(pc)))
+// free the **char created by cStringArray
+func freeCStringArray(arry **C.char, n int) {
+	var pc *C.char
+	for ii := 0; ii < n; ii++ {
+		p := (**C.char)(unsafe.Pointer(uintptr(unsafe.Pointer(arry)) + uintptr(ii)*unsafe.Sizeof(pc)))
 		C.free(unsafe.Pointer(*p))
 	}
-	C.free(unsafe.Pointer(keys))
+	C.free(unsafe.Pointer(arry))
+}
 
-	if err != 0 {
-		return nil, GetLastError()
+func (p *Predictor) GetOptions() *options.Options {
+	return p.options
+}
+
+// Create a new predictor with different input shapes that shares the weights of p
+// go binding for MXPredReshape
+// param shapes The new shape of each input node to change, keyed by the input node name.
+// Input nodes not in shapes keep their current shape.
+// The options of the returned predictor have the new input shapes, and the batch size
+// of the first input node.
+func (p *Predictor) Reshape(shapes map[string][]int) (*Predictor, error) {
+	if len(shapes) == 0 {
+		return nil, newError("MXPredReshape", ErrInvalidShape, "no input shapes to reshape")
 	}
 
-	pred := &Predictor{handle: handle, options: options}
+	nodes := make([]options.Node, len(p.options.InputNodes()))
+	found := 0
+	for ii, nd := range p.options.InputNodes() {
+		nodes[ii] = nd
+		shape, ok := shapes[nd.Key]
+		if !ok {
+			continue
+		}
+		if len(shape) == 0 {
+			return nil, newError("MXPredReshape", ErrInvalidShape, "empty shape for input %s", nd.Key)
+		}
+		nodes[ii].Shape = append([]int{}, shape...)
+		found++
+	}
+	if found != len(shapes) {
+		for key := range shapes {
+			if !hasInputNode(p.options.InputNodes(), key) {
+				return nil, newError("MXPredReshape", ErrUnknownInput, "the predictor has no input node %s", key)
+			}
+		}
+	}
 
-	runtime.SetFinalizer(pred, (*Predictor).finalizer)
+	inputKeys, shapeIdx, shapeData := inputShapes(nodes)
+	keys := cStringArray(inputKeys)
+	defer freeCStringArray(keys, len(inputKeys))
 
-	return pred, nil
+	var handle C.PredictorHandle
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkClosed("MXPredReshape"); err != nil {
+		return nil, err
+	}
+
+	err := p.exec.do("MXPredReshape", func() error {
+		success := C.MXPredReshape(
+			C.mx_uint(len(nodes)),
+			keys,
+			(*C.mx_uint)(unsafe.Pointer(&shapeIdx[0])),
+			(*C.mx_uint)(unsafe.Pointer(&shapeData[0])),
+			p.handle,
+			&handle,
+		)
+		if success != 0 {
+			return lastError("MXPredReshape")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.New(
+		options.WithOptions(p.options),
+		options.InputNodes(nodes),
+		options.BatchSize(nodes[0].Shape[0]),
+	)
+
+	// the new handle is independent of p, and gets its own thread
+	return newPredictor(handle, opts, p.outputNames, newExecutor()), nil
 }
 
-func (p *Predictor) GetOptions() *options.Options {
-  return p.options
+func hasInputNode(nodes []options.Node, key string) bool {
+	for _, nd := range nodes {
+		if nd.Key == key {
+			return true
+		}
+	}
+	return false
 }
 
 // set the input data of predictor
 // go binding for MXPredSetInput
 // param key The name of input node to set
-// param data The float data to be set
+// param data The data to be set, converted to float32 if needed
+// The dtype of the data must match the dtype of the input node when it is set
 func (p *Predictor) SetInput(key string, input *gotensor.Dense) error {
-	k := C.CString(key)
-	// free mem before return
-  defer C.free(unsafe.Pointer(k))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.setInput(context.Background(), key, input)
+}
 
-	success := C.MXPredSetInput(
-		p.handle,
-		k,
-		(*C.mx_float)(input.Pointer()),
-		C.mx_uint(input.Size()),
-	)
+func (p *Predictor) setInput(ctx context.Context, key string, input *gotensor.Dense) error {
+	if err := p.checkClosed("MXPredSetInput"); err != nil {
+		return err
+	}
 
-	if success != 0 {
-		return GetLastError()
+	span, recorded := p.startFrameworkSpan(ctx, "c_set_input")
+	defer span.Finish()
+	if recorded {
+		span.SetTag("input", key)
+		span.SetTag("shape", input.Shape())
+		span.SetTag("dtype", input.Dtype().String())
+		span.SetTag("size_bytes", input.Size()*int(input.Dtype().Size()))
+		span.SetTag("float_bytes", input.Size()*4)
 	}
-	return nil
+	for _, nd := range p.options.InputNodes() {
+		if nd.Key == key && nd.Dtype.Type != nil && nd.Dtype != input.Dtype() {
+			return inputError(key, ErrUnsupportedDtype, "got dtype %v, expecting %v", input.Dtype(), nd.Dtype)
+		}
+	}
+	if !isSupportedDtype(input.Dtype()) {
+		return inputError(key, ErrUnsupportedDtype, "%v", input.Dtype())
+	}
+
+	data, err := toFloat32s(input)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return inputError(key, ErrInvalidShape, "input data is empty")
+	}
+
+	k := C.CString(key)
+	// free mem before return
+	defer C.free(unsafe.Pointer(k))
+
+	return p.exec.do("MXPredSetInput", func() error {
+		success := C.MXPredSetInput(
+			p.handle,
+			k,
+			(*C.mx_float)(unsafe.Pointer(&data[0])),
+			C.mx_uint(len(data)),
+		)
+		if success != 0 {
+			return lastError("MXPredSetInput")
+		}
+		return nil
+	})
 }
 
 // run a forward pass after SetInput
 // go binding for MXPredForward
 func (p *Predictor) Forward() error {
-	success := C.MXPredForward(p.handle)
-	if success != 0 {
-		return GetLastError()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.forward(context.Background())
+}
+
+func (p *Predictor) forward(ctx context.Context) error {
+	if err := p.checkClosed("MXPredForward"); err != nil {
+		return err
 	}
-	return WaitAll()
+	p.outputs = nil
+
+	span, _ := p.startFrameworkSpan(ctx, "c_forward")
+	defer span.Finish()
+	return p.exec.do("MXPredForward", func() error {
+		success := C.MXPredForward(p.handle)
+		if success != 0 {
+			return lastError("MXPredForward")
+		}
+		return waitAll()
+	})
 }
 
+// Predict sets the input data and runs a forward pass.
+// The data is validated against the input nodes before anything is passed to mxnet,
+// see validateBatch, and an *InputError naming the offending input node is returned
+// when it does not match.
+// The data can hold any number of samples along the leading (batch) dimension of the
+// input nodes. When it does not hold the bound batch size, the samples are run in
+// chunks of the bound batch size, the last one zero padded, and the outputs read
+// afterwards are concatenated and trimmed to the number of samples, see predictChunks.
+// The context is checked between the stages, including while waiting for a prediction
+// in flight on the predictor, and ctx.Err() is returned once it is done.
+// The forward pass itself cannot be interrupted: if ctx is done while it runs,
+// Predict returns right away and the result of the forward pass is thrown away.
+// Later calls on the predictor wait for that forward pass to complete.
+// The data is copied before the forward pass starts, so it can be modified once
+// Predict returns, even while an abandoned forward pass is still running.
 func (p *Predictor) Predict(ctx context.Context, data []*gotensor.Dense) error {
-  if len(data) == 0 {
-		return errors.New("intput data nil or empty")
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	for ii, inputNode := range p.options.InputNodes() {
-		if inputNode.Key == "" {
-			return errors.New("expecting a valid (non-empty) input layer name")
+	samples, batchSize, err := validateBatch(p.options.InputNodes(), data)
+	if err != nil {
+		return err
+	}
+
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_predict",
+		opentracing.Tags{
+			"evaluation_trace_level": p.GetOptions().TraceLevel(),
+		})
+	defer span.Finish()
+
+	// the goroutine owns the lock, and releases it when the forward pass completes
+	if err := p.lockContext(ctx); err != nil {
+		return err
+	}
+	data = copyInputs(data)
+	done := make(chan error, 1)
+	go func() {
+		defer p.mu.Unlock()
+		done <- p.predict(ctx, data, samples, batchSize)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		span.SetTag("abandoned", true)
+		return ctx.Err()
+	}
+}
+
+// copyInputs returns copies of the input tensors, views are materialized.
+// nil inputs are kept, for the validation to report them.
+func copyInputs(data []*gotensor.Dense) []*gotensor.Dense {
+	res := make([]*gotensor.Dense, len(data))
+	for ii, input := range data {
+		if input == nil {
+			continue
+		}
+		if input.IsMaterializable() {
+			res[ii] = materialize(input)
+			continue
 		}
+		res[ii] = input.Clone().(*gotensor.Dense)
+	}
+	return res
+}
+
+// lockContext acquires mu, or fails with ctx.Err() if ctx is done first.
+// The lock is released as soon as it is acquired when the wait is abandoned.
+func (p *Predictor) lockContext(ctx context.Context) error {
+	locked := make(chan struct{})
+	go func() {
+		p.mu.Lock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			p.mu.Unlock()
+		}()
+		return ctx.Err()
+	}
+}
+
+// predict runs the validated inputs holding samples, in chunks if they do not hold
+// the bound batch size. It must be called with mu held.
+func (p *Predictor) predict(ctx context.Context, data []*gotensor.Dense, samples, batchSize int) error {
+	if samples == batchSize {
+		return p.predictBatch(ctx, data)
+	}
+	return p.predictChunks(ctx, data, samples, batchSize)
+}
 
-		err := p.SetInput(inputNode.Key, data[ii])
+// predictBatch sets the inputs and runs the forward pass, it must be called with mu held
+// and the inputs matching the input nodes
+func (p *Predictor) predictBatch(ctx context.Context, data []*gotensor.Dense) error {
+	for ii, inputNode := range p.options.InputNodes() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := p.setInput(ctx, inputNode.Key, data[ii])
 		if err != nil {
 			return err
 		}
 	}
 
-  span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_predict", 		
-  opentracing.Tags{
-    "evaluation_trace_level": p.GetOptions().TraceLevel(),
-  })
-  defer span.Finish()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-  if p.GetOptions().TraceLevel() >= tracer.FRAMEWORK_TRACE {
+	var profile *Profile
+	if p.GetOptions().TraceLevel() >= tracer.FRAMEWORK_TRACE {
 		// define profiling options
 		poptions := map[string]ProfileMode{
 			"profile_all":        ProfileAllDisable,
@@ -196,38 +634,54 @@ func (p *Predictor) Predict(ctx context.Context, data []*gotensor.Dense) error {
 			"profile_api":        ProfileApiDisable,
 			"continuous_dump":    ProfileContinuousDumpDisable,
 		}
-		if profile, err := NewProfile(poptions, filepath.Join("/tmp", "profile")); err == nil {
+		if prof, err := NewProfile(poptions, filepath.Join("/tmp", "profile")); err == nil {
+			profile = prof
 			profile.Start()
-			defer func() {
-				profile.Stop()
-				profile.Publish(ctx)
-				profile.Delete()
-			}()
 		}
 	}
 
-  err := p.cuptiStart(ctx)
-	if err != nil {
-		return err
-	}
-
-	err = p.Forward()
+	err := p.cuptiStart(ctx)
 	if err != nil {
 		return err
 	}
 
+	err = p.forward(ctx)
 	p.cuptiClose()
+	if profile != nil {
+		profile.Stop()
+		profile.Publish(ctx)
+		profile.Delete()
+	}
+	return err
+}
 
-	return nil
+// startFrameworkSpan starts a span of a C call, tagged with the device and the batch size
+// of the predictor. recorded is false for the noop span started when the call is not
+// traced, the caller only tags recorded spans so that nothing is built for the tags then.
+func (p *Predictor) startFrameworkSpan(ctx context.Context, operationName string) (span opentracing.Span, recorded bool) {
+	span, _ = tracer.StartSpanFromContext(ctx, tracer.FRAMEWORK_TRACE, operationName)
+	if _, noop := span.Tracer().(opentracing.NoopTracer); noop {
+		return span, false
+	}
+	if p.options.UsesGPU() {
+		span.SetTag("device", "gpu")
+	} else {
+		span.SetTag("device", "cpu")
+	}
+	span.SetTag("batch_size", p.options.BatchSize())
+	if devices := p.options.Devices(); len(devices) != 0 {
+		span.SetTag("device_id", devices[0].ID())
+	}
+	return span, true
 }
 
 func (p *Predictor) cuptiStart(ctx context.Context) error {
-  opts := p.GetOptions()
+	opts := p.GetOptions()
 	if !opts.UsesGPU() || opts.TraceLevel() < tracer.SYSTEM_LIBRARY_TRACE {
 		return nil
-  }
+	}
 
-  metrics := []string{}
+	metrics := []string{}
 	if opts.GPUMetrics() != "" {
 		metrics = strings.Split(opts.GPUMetrics(), ",")
 	}
@@ -240,7 +694,7 @@ func (p *Predictor) cuptiStart(ctx context.Context) error {
 		return err
 	}
 	p.cu = cu
-  return nil 
+	return nil
 }
 
 func (p *Predictor) cuptiClose() {
@@ -252,58 +706,92 @@ func (p *Predictor) cuptiClose() {
 	p.cu = nil
 }
 
-
 // get the shape of output node
 // go binding for MXPredGetOutputShape
 // param index The index of output node, set to 0 if there is only one output
 func (p *Predictor) GetOutputShape(index int) ([]int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.getOutputShape(context.Background(), index)
+}
+
+func (p *Predictor) getOutputShape(ctx context.Context, index int) ([]int, error) {
+	if err := p.checkClosed("MXPredGetOutputShape"); err != nil {
+		return nil, err
+	}
+	if p.outputs != nil {
+		return append([]int{}, p.outputs[index].Shape()...), nil
+	}
+
+	span, recorded := p.startFrameworkSpan(ctx, "c_get_output_shape")
+	defer span.Finish()
+	if recorded {
+		span.SetTag("output", index)
+	}
 	var (
 		shapeData *C.mx_uint = nil
 		shapeDim  C.mx_uint  = 0
 	)
-	success := C.MXPredGetOutputShape(
-		p.handle,
-		C.mx_uint(index),
-		&shapeData,
-		&shapeDim,
-	)
-	if success != 0 {
-		return nil, GetLastError()
+	var res []int
+	err := p.exec.do("MXPredGetOutputShape", func() error {
+		success := C.MXPredGetOutputShape(
+			p.handle,
+			C.mx_uint(index),
+			&shapeData,
+			&shapeDim,
+		)
+		if success != 0 {
+			return lastError("MXPredGetOutputShape")
+		}
+		// c array to go, copied before the next call on the handle overwrites it
+		shape := (*[1 << 32]C.mx_uint)(unsafe.Pointer(shapeData))[:shapeDim:shapeDim]
+		res = make([]int, shapeDim)
+		for ii, s := range shape {
+			res[ii] = int(s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	// c array to go
-	shape := (*[1 << 32]C.mx_uint)(unsafe.Pointer(shapeData))[:shapeDim:shapeDim]
-	res := make([]int, shapeDim)
-	for ii, s := range shape {
-		res[ii] = int(s)
+	if recorded {
+		span.SetTag("shape", res)
 	}
 	return res, nil
 }
 
+// read the output at index, converted from float32 to the dtype of the output node
+// go binding for MXPredGetOutput
 func (p *Predictor) ReadPredictionOutputAtIndex(ctx context.Context, index int) (gotensor.Tensor, error) {
-	node := p.options.OutputNodes()[index]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readPredictionOutputAtIndex(ctx, index)
+}
 
-	if node.Dtype != gotensor.Float32 {
-		panic("only supports float32 for now")
-  }
-  
-	shape, err := p.GetOutputShape(index)
+func (p *Predictor) readPredictionOutputAtIndex(ctx context.Context, index int) (gotensor.Tensor, error) {
+	if err := p.checkOutputIndex(index); err != nil {
+		return nil, err
+	}
+	dtype := nodeDtype(p.options.OutputNodes()[index])
+	if !isSupportedDtype(dtype) {
+		return nil, newError("MXPredGetOutput", ErrUnsupportedDtype, "output %d has dtype %v", index, dtype)
+	}
+
+	shape, err := p.getOutputShape(ctx, index)
 	if err != nil {
 		return nil, err
 	}
 
-	size := prod(shape)
-	output := make([]float32, size)
-	success := C.MXPredGetOutput(
-		p.handle,
-		C.mx_uint(index),
-		(*C.mx_float)(unsafe.Pointer(&output[0])),
-		C.mx_uint(size),
-	)
-	if success != 0 {
-		return nil, GetLastError()
-  }
-  
-	return gotensor.NewDense(node.Dtype, shape, gotensor.WithBacking(output)), nil
+	output := make([]float32, prod(shape))
+	if err := p.getOutput(ctx, index, output); err != nil {
+		return nil, err
+	}
+
+	tensor, err := fromFloat32s(dtype, shape, output)
+	if err != nil {
+		return nil, err
+	}
+	return tensor, nil
 }
 
 // get the output of the prediction
@@ -312,11 +800,18 @@ func (p *Predictor) ReadPredictionOutputs(ctx context.Context) ([]gotensor.Tenso
 	span, _ := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_read_prediction_output")
 	defer span.Finish()
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.readPredictionOutputs(ctx)
+}
+
+func (p *Predictor) readPredictionOutputs(ctx context.Context) ([]gotensor.Tensor, error) {
 	outputNodes := p.options.OutputNodes()
 	res := make([]gotensor.Tensor, len(outputNodes))
 
 	for ii := 0; ii < len(outputNodes); ii++ {
-		tensor, err := p.ReadPredictionOutputAtIndex(ctx, ii)
+		tensor, err := p.readPredictionOutputAtIndex(ctx, ii)
 		if err != nil {
 			return nil, err
 		}
@@ -326,24 +821,55 @@ func (p *Predictor) ReadPredictionOutputs(ctx context.Context) ([]gotensor.Tenso
 	return res, nil
 }
 
-func (p *Predictor) finalizer() error {
+// checkClosed must be called with mu held
+func (p *Predictor) checkClosed(fn string) error {
+	if p.closed {
+		return newError(fn, ErrPredictorClosed, "")
+	}
+	return nil
+}
+
+// finalizer frees the handle of a predictor collected by the GC without being closed
+func (p *Predictor) finalizer() {
+	if p.closed {
+		return
+	}
+	recordLeak(p)
+	p.free()
+}
+
+func (p *Predictor) free() error {
 	if p.handle == nil {
+		p.exec.close()
 		return nil
 	}
-	success := C.MXPredFree(p.handle)
-	if success != 0 {
-		return GetLastError()
-	}
-	return nil
+	err := p.exec.do("MXPredFree", func() error {
+		success := C.MXPredFree(p.handle)
+		if success != 0 {
+			return lastError("MXPredFree")
+		}
+		return nil
+	})
+	p.exec.close()
+	p.handle = nil
+	return err
 }
 
 // free this predictor's C handle
 // go binding for MXPredFree
+// Close can be called more than once and from several goroutines, only the first call
+// frees the handle. It waits for a running forward pass to complete. The other methods
+// return an ErrPredictorClosed error once the predictor is closed.
 func (p *Predictor) Close() error {
 	if p == nil {
 		return nil
 	}
-	err := p.finalizer()
-	p.handle = nil
-	return err
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	runtime.SetFinalizer(p, nil)
+	return p.free()
 }
@@ -0,0 +1,80 @@
+package mxnet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/go-mxnet/params"
+	gotensor "gorgonia.org/tensor"
+)
+
+// fcModel returns the symbol and the zero weights of a fully connected layer
+// from inputs to hidden entries per sample
+func fcModel(t *testing.T, inputs, hidden int) ([]byte, []byte) {
+	t.Helper()
+	symbol := fmt.Sprintf(`{
+  "nodes": [
+    {"op": "null", "name": "data", "inputs": []},
+    {"op": "null", "name": "fc_weight", "attrs": {"num_hidden": "%[1]d"}, "inputs": []},
+    {"op": "null", "name": "fc_bias", "attrs": {"num_hidden": "%[1]d"}, "inputs": []},
+    {"op": "FullyConnected", "name": "fc", "attrs": {"num_hidden": "%[1]d"}, "inputs": [[0, 0, 0], [1, 0, 0], [2, 0, 0]]}
+  ],
+  "arg_nodes": [0, 1, 2],
+  "heads": [[3, 0, 0]],
+  "attrs": {"mxnet_version": ["int", 10300]}
+}`, hidden)
+	weights, err := params.Encode([]params.Array{
+		{Name: "arg:fc_weight", Tensor: gotensor.New(gotensor.WithShape(hidden, inputs), gotensor.WithBacking(make([]float32, hidden*inputs)))},
+		{Name: "arg:fc_bias", Tensor: gotensor.New(gotensor.WithShape(hidden), gotensor.WithBacking(make([]float32, hidden)))},
+	})
+	if err != nil {
+		t.Fatalf("params.Encode: %v", err)
+	}
+	return []byte(symbol), weights
+}
+
+// newTestPredictor creates a cpu predictor of fcModel, the caller closes it
+func newTestPredictor(t *testing.T, batchSize, inputs, hidden int) *Predictor {
+	t.Helper()
+	symbol, weights := fcModel(t, inputs, hidden)
+	pred, err := New(context.Background(),
+		options.Device(options.CPU_DEVICE, 0),
+		options.Graph(symbol),
+		options.Weights(weights),
+		options.BatchSize(batchSize),
+		options.InputNodes([]options.Node{{Key: "data", Shape: []int{batchSize, inputs}}}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return pred
+}
+
+// run with -race: the forward pass of an abandoned Predict must not read the
+// inputs once Predict returns
+func TestPredictCanceledInputs(t *testing.T) {
+	pred := newTestPredictor(t, 64, 1024, 1024)
+	defer pred.Close()
+
+	data := float32Tensor([]int{64, 1024}, make([]float32, 64*1024)...)
+	for ii := 0; ii < 20; ii++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(ii*50)*time.Microsecond)
+		err := pred.Predict(ctx, []*gotensor.Dense{data})
+		cancel()
+		if err != nil && err != context.DeadlineExceeded {
+			t.Fatalf("Predict: %v", err)
+		}
+		values := data.Data().([]float32)
+		for jj := range values {
+			values[jj] = float32(ii)
+		}
+	}
+
+	// waits for the abandoned forward passes
+	if err := pred.Predict(context.Background(), []*gotensor.Dense{data}); err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+}
@@ -67,7 +67,12 @@ var (
 // go binding for MXSetProfilerConfig()
 // param profile_options map of profiling options
 // param tmpDir output filepath
+// It fails with ErrFeatureMissing if libmxnet was built without the profiler, or
+// ErrFeatureUnknown if libmxnet does not report it.
 func NewProfile(profileOptions map[string]ProfileMode, tmpDir string) (*Profile, error) {
+	if err := requireFeature("MXSetProfilerConfig", "PROFILER"); err != nil {
+		return nil, err
+	}
 
 	// convert go data structures into c data structures
 	ckeys := C.malloc(C.size_t(64) * C.size_t(unsafe.Sizeof(uintptr(0))))
@@ -100,10 +105,13 @@ func NewProfile(profileOptions map[string]ProfileMode, tmpDir string) (*Profile,
 	}
 
 	fileName := string(profileOptions["filename"])
-	success := C.MXSetProfilerConfig(C.int(keyLen), (**C.char)(ckeys), (**C.char)(cvals))
-	if success != 0 {
-		return nil, errors.Wrap(GetLastError(), "failed to set profiler configuration")
-	}
+	err := defaultExecutor().do("MXSetProfilerConfig", func() error {
+		success := C.MXSetProfilerConfig(C.int(keyLen), (**C.char)(ckeys), (**C.char)(cvals))
+		if success != 0 {
+			return lastError("MXSetProfilerConfig")
+		}
+		return nil
+	})
 
 	// free C pointers
 	for ii := 0; ii < keyLen; ii++ {
@@ -113,6 +121,10 @@ func NewProfile(profileOptions map[string]ProfileMode, tmpDir string) (*Profile,
 	C.free(unsafe.Pointer(ckeys))
 	C.free(unsafe.Pointer(cvals))
 
+	if err != nil {
+		return nil, err
+	}
+
 	return &Profile{
 		Trace:    nil,
 		filename: fileName,
@@ -124,13 +136,19 @@ func NewProfile(profileOptions map[string]ProfileMode, tmpDir string) (*Profile,
 
 // go binding for MXSetProfilerState(1)
 func (p *Profile) Start() error {
-	success, err := C.MXSetProfilerState(C.int(1))
+	err := defaultExecutor().do("MXSetProfilerState", func() error {
+		success, err := C.MXSetProfilerState(C.int(1))
+		if err != nil {
+			return err
+		}
+		if success != 0 {
+			return lastError("MXSetProfilerState")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if success != 0 {
-		return GetLastError()
-	}
 	p.startTime = time.Now()
 	p.started = true
 
@@ -140,7 +158,7 @@ func (p *Profile) Start() error {
 // go binding for MXSetProfilerState(0)
 func (p *Profile) Stop() error {
 	if !p.started {
-		return errors.New("mxnet profile was not started")
+		return newError("MXSetProfilerState", ErrProfileNotStarted, "")
 	}
 	if p.stopped == true {
 		return nil
@@ -150,13 +168,19 @@ func (p *Profile) Stop() error {
 	}()
 	WaitAll()
 	p.endTime = time.Now()
-	success, err := C.MXSetProfilerState(C.int(0))
+	err := defaultExecutor().do("MXSetProfilerState", func() error {
+		success, err := C.MXSetProfilerState(C.int(0))
+		if err != nil {
+			return err
+		}
+		if success != 0 {
+			return lastError("MXSetProfilerState")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if success != 0 {
-		return GetLastError()
-	}
 
 	return nil
 }
@@ -164,7 +188,7 @@ func (p *Profile) Stop() error {
 // go binding for MXProfilePause(1)
 func (p *Profile) Pause() error {
 	if !p.started {
-		return errors.New("mxnet profile was not started")
+		return newError("MXProfilePause", ErrProfileNotStarted, "")
 	}
 	if p.stopped == true || p.paused == true {
 		return nil
@@ -173,13 +197,19 @@ func (p *Profile) Pause() error {
 		p.paused = true
 	}()
 	p.lastPauseTime = time.Now()
-	success, err := C.MXProfilePause(C.int(1))
+	err := defaultExecutor().do("MXProfilePause", func() error {
+		success, err := C.MXProfilePause(C.int(1))
+		if err != nil {
+			return err
+		}
+		if success != 0 {
+			return lastError("MXProfilePause")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if success != 0 {
-		return GetLastError()
-	}
 
 	return nil
 }
@@ -187,7 +217,7 @@ func (p *Profile) Pause() error {
 // go binding for MXProfilePause(0)
 func (p *Profile) Resume() error {
 	if !p.started {
-		return errors.New("mxnet profile was not started")
+		return newError("MXProfilePause", ErrProfileNotStarted, "")
 	}
 	if p.stopped == true || p.paused == false {
 		return nil
@@ -196,13 +226,19 @@ func (p *Profile) Resume() error {
 		p.paused = false
 	}()
 	p.lastResumeTime = time.Now()
-	success, err := C.MXProfilePause(C.int(0))
+	err := defaultExecutor().do("MXProfilePause", func() error {
+		success, err := C.MXProfilePause(C.int(0))
+		if err != nil {
+			return err
+		}
+		if success != 0 {
+			return lastError("MXProfilePause")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if success != 0 {
-		return GetLastError()
-	}
 
 	return nil
 }
@@ -210,7 +246,7 @@ func (p *Profile) Resume() error {
 // go binding for MXDumpProfile()
 func (p *Profile) Dump(finished bool) (string, error) {
 	if !p.started {
-		return "", errors.New("mxnet profile was not started")
+		return "", newError("MXDumpProfile", ErrProfileNotStarted, "")
 	}
 	if !p.stopped {
 		return "", errors.New("mxnet profile was not stopped")
@@ -225,13 +261,19 @@ func (p *Profile) Dump(finished bool) (string, error) {
 	if finished {
 		fin = 1
 	}
-	success, err := C.MXDumpProfile(C.int(fin))
+	err := defaultExecutor().do("MXDumpProfile", func() error {
+		success, err := C.MXDumpProfile(C.int(fin))
+		if err != nil {
+			return err
+		}
+		if success != 0 {
+			return lastError("MXDumpProfile")
+		}
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
-	if success != 0 {
-		return "", GetLastError()
-	}
 
 	return p.filename, nil
 }
@@ -261,7 +303,7 @@ func (p *Profile) Read() error {
 	}
 
 	if !p.started {
-		return errors.New("mxnet profile was not started")
+		return newError("MXDumpProfile", ErrProfileNotStarted, "")
 	}
 	if !p.stopped {
 		if err := p.Stop(); err != nil {
@@ -311,9 +353,9 @@ func (p *Profile) process() {
 	start := p.startTime
 
 	minTime := int64(0)
-  events := []chrome.TraceEvent{}
+	events := []chrome.TraceEvent{}
 	for _, event := range p.Trace.TraceEvents {
-    eventType := event.EventType
+		eventType := event.EventType
 		if eventType != "B" && eventType != "E" {
 			continue
 		}
@@ -333,8 +375,8 @@ func (p *Profile) process() {
 		}
 	}
 
-  layerSequenceIndex := 0
-  visited := map[string]bool{}
+	layerSequenceIndex := 0
+	visited := map[string]bool{}
 	for ii, event := range events {
 		events[ii].Name = strings.Trim(strings.Trim(event.Name, "["), "]")
 		if adjustTime {
@@ -347,31 +389,31 @@ func (p *Profile) process() {
 		}
 		if event.Category != "operator" {
 			continue
-    }
+		}
 
-    opName, layerName, shape := parseOpLabel(event.Name)
+		opName, layerName, shape := parseOpLabel(event.Name)
 
 		events[ii].Args["layer_sequence_index"] = layerSequenceIndex
-    events[ii].Args["layer_name"] = layerName
-    events[ii].Args["op_name"] = opName
-    events[ii].Args["shape"] = shape
-    events[ii].Args["name"] = event.Name
-    events[ii].Name = layerName
-    
-    _, ok := visited[event.Name]
-    if !ok {
-      layerSequenceIndex += 1
-      visited[event.Name] = true
-    }
-  }
-  
+		events[ii].Args["layer_name"] = layerName
+		events[ii].Args["op_name"] = opName
+		events[ii].Args["shape"] = shape
+		events[ii].Args["name"] = event.Name
+		events[ii].Name = layerName
+
+		_, ok := visited[event.Name]
+		if !ok {
+			layerSequenceIndex += 1
+			visited[event.Name] = true
+		}
+	}
+
 	p.Trace.TraceEvents = events
 }
 
 func (p *Profile) Delete() error {
 	if !com.IsFile(p.filename) {
 		return nil
-  }
+	}
 	return os.Remove(p.filename)
 }
 
@@ -423,10 +465,17 @@ func (p *Profile) addNodeMetadata(pth string) {
 	p.Trace.TraceEvents = events
 }
 
+// wait for all the pending mxnet operations to complete
+// go binding for MXNDArrayWaitAll
 func WaitAll() error {
+	return defaultExecutor().do("MXNDArrayWaitAll", waitAll)
+}
+
+// waitAll must run on an executor, so that the error is read on the thread that failed
+func waitAll() error {
 	success := C.MXNDArrayWaitAll()
 	if success != 0 {
-		return GetLastError()
+		return lastError("MXNDArrayWaitAll")
 	}
 	return nil
 }
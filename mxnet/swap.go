@@ -0,0 +1,178 @@
+package mxnet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rai-project/dlframework/framework/options"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// SwappablePredictor is a predictor whose weights can be reloaded while it serves requests.
+// Requests run on the current generation of the predictor. Reload builds the next
+// generation next to it, and swaps it in once it passed a warmup; the requests still
+// running on the previous generation complete before its handle is freed.
+type SwappablePredictor struct {
+	mu      sync.RWMutex
+	current *generation
+	closed  bool
+	// the options passed by the caller, without the weights and the discovered nodes
+	options *options.Options
+	// serializes the reloads
+	reload sync.Mutex
+	// the generations that are waiting for their requests to complete before closing
+	retiring sync.WaitGroup
+}
+
+type generation struct {
+	predictor *Predictor
+	inflight  sync.WaitGroup
+}
+
+// NewSwappablePredictor creates the first generation of the predictor, see New
+func NewSwappablePredictor(ctx context.Context, opts ...options.Option) (*SwappablePredictor, error) {
+	pred, err := New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SwappablePredictor{
+		current: &generation{predictor: pred},
+		options: options.New(
+			options.WithOptions(options.New(opts...)),
+			options.Weights(nil),
+		),
+	}, nil
+}
+
+func (s *SwappablePredictor) GetOptions() *options.Options {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.predictor.GetOptions()
+}
+
+// acquire returns the current generation, which is not closed before release is called
+func (s *SwappablePredictor) acquire() (*generation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, newError("MXPredForward", ErrPredictorClosed, "")
+	}
+	gen := s.current
+	gen.inflight.Add(1)
+	return gen, nil
+}
+
+func (gen *generation) release() {
+	gen.inflight.Done()
+}
+
+// Predict runs the prediction on the current generation and returns its outputs.
+// The inputs are set, the forward pass run and the outputs read under one lock, so
+// concurrent requests get the outputs of their own inputs.
+// If ctx is done first, Predict returns ctx.Err() right away; the inputs are copied,
+// and the generation is not closed before the abandoned prediction completes.
+func (s *SwappablePredictor) Predict(ctx context.Context, data []*gotensor.Dense) ([]gotensor.Tensor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	gen, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	pred := gen.predictor.PredictAsync(ctx, copyInputs(data))
+	go func() {
+		<-pred.Done()
+		gen.release()
+	}()
+
+	select {
+	case <-pred.Done():
+		return pred.outputs, pred.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reload creates a predictor with the new weights and the options NewSwappablePredictor
+// was called with, the nodes missing from those options are discovered from the new
+// weights. It checks it with warmupIterations forward passes (at least one), then swaps it in.
+// The previous predictor is closed once the requests running on it complete.
+// The current predictor keeps serving requests if Reload fails.
+func (s *SwappablePredictor) Reload(ctx context.Context, weights []byte, warmupIterations int) error {
+	if len(weights) == 0 {
+		return newError("MXPredCreate", ErrInvalidOptions, "empty weights")
+	}
+	if warmupIterations < 1 {
+		warmupIterations = 1
+	}
+
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_reload",
+		opentracing.Tags{
+			"warmup_iterations": warmupIterations,
+		})
+	defer span.Finish()
+
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return newError("MXPredCreate", ErrPredictorClosed, "")
+	}
+
+	pred, err := New(ctx, options.WithOptions(s.options), options.Weights(weights))
+	if err != nil {
+		return err
+	}
+	if _, err := pred.Warmup(ctx, warmupIterations); err != nil {
+		pred.Close()
+		return fmt.Errorf("the reloaded predictor failed the warmup: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		pred.Close()
+		return newError("MXPredCreate", ErrPredictorClosed, "")
+	}
+	old := s.current
+	s.current = &generation{predictor: pred}
+	s.mu.Unlock()
+
+	s.retire(old)
+	return nil
+}
+
+// retire closes the predictor of gen in the background once its requests complete.
+// gen must no longer be the current generation, so that no request is added to it.
+func (s *SwappablePredictor) retire(gen *generation) {
+	s.retiring.Add(1)
+	go func() {
+		defer s.retiring.Done()
+		gen.inflight.Wait()
+		gen.predictor.Close()
+	}()
+}
+
+// Close waits for the running requests to complete and closes the predictors
+func (s *SwappablePredictor) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	gen := s.current
+	s.mu.Unlock()
+
+	gen.inflight.Wait()
+	err := gen.predictor.Close()
+	s.retiring.Wait()
+	return err
+}
@@ -0,0 +1,48 @@
+package mxnet
+
+/*
+// go preamble
+typedef struct MXCallbackList MXCallbackList;
+#include <mxnet/c_api.h>
+#include <stdlib.h>
+*/
+import "C"
+import "unsafe"
+
+// symbolOutputNames returns the names of the outputs of the symbol, in the order of
+// the graph heads, as mxnet names them: variables are named after the variable, and
+// operator outputs are named <name>_<output name> (e.g. fc1_output, softmax_output0
+// or bn_mean), see Graph.OutputNames.
+// go binding for MXSymbolCreateFromJSON and MXSymbolListOutputs
+func symbolOutputNames(symbol []byte) ([]string, error) {
+	json := C.CString(string(symbol))
+	defer C.free(unsafe.Pointer(json))
+
+	var names []string
+	err := defaultExecutor().do("MXSymbolListOutputs", func() error {
+		var handle C.SymbolHandle
+		if success := C.MXSymbolCreateFromJSON(json, &handle); success != 0 {
+			return lastError("MXSymbolCreateFromJSON")
+		}
+		defer C.MXSymbolFree(handle)
+
+		var (
+			size C.mx_uint
+			arry **C.char
+		)
+		if success := C.MXSymbolListOutputs(handle, &size, &arry); success != 0 {
+			return lastError("MXSymbolListOutputs")
+		}
+		// the array is owned by mxnet and reused by the next call on the thread
+		strs := (*[1 << 28]*C.char)(unsafe.Pointer(arry))[:size:size]
+		names = make([]string, size)
+		for ii, str := range strs {
+			names[ii] = C.GoString(str)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
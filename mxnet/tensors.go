@@ -0,0 +1,98 @@
+package mxnet
+
+import (
+	"unsafe"
+
+	gotensor "gorgonia.org/tensor"
+)
+
+// materialize copies a view (a slice or a transpose) into a new tensor, whose data holds
+// the elements of the view in row-major order. The data of a view is the data it was
+// taken from. Other tensors are returned as is.
+func materialize(t *gotensor.Dense) *gotensor.Dense {
+	if !t.IsMaterializable() {
+		return t
+	}
+	return t.Materialize().(*gotensor.Dense)
+}
+
+// the raw bytes of the elements of a dense tensor in row-major order. They back the tensor,
+// without a copy, unless the tensor is a view: the data of a view is materialized first.
+func denseBytes(t *gotensor.Dense) []byte {
+	t = materialize(t)
+	n := t.Size() * int(t.Dtype().Size())
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 40]byte)(t.Pointer())[:n:n]
+}
+
+// stackBatch creates a tensor of the given dtype and shape, whose leading dimension is the batch,
+// and copies the entries of the samples into consecutive batch entries. A sample holds one or
+// more batch entries. The batch entries after the samples are zero.
+func stackBatch(dtype gotensor.Dtype, shape []int, samples []*gotensor.Dense) (*gotensor.Dense, error) {
+	if len(shape) == 0 {
+		return nil, newError("", ErrInvalidShape, "cannot stack samples into a scalar")
+	}
+	res := gotensor.New(gotensor.Of(dtype), gotensor.WithShape(shape...))
+	dst := denseBytes(res)
+	if len(dst) == 0 {
+		return res, nil
+	}
+	entryBytes := len(dst) / shape[0]
+	offset := 0
+	for ii, sample := range samples {
+		if sample.Dtype() != dtype {
+			return nil, newError("", ErrUnsupportedDtype, "cannot stack a %v sample into a %v batch", sample.Dtype(), dtype)
+		}
+		src := denseBytes(sample)
+		if len(src)%entryBytes != 0 {
+			return nil, newError("", ErrInvalidShape, "sample %d has %d elements, expecting a multiple of %d",
+				ii, sample.Size(), prod(shape[1:]))
+		}
+		if offset+len(src) > len(dst) {
+			return nil, newError("", ErrInvalidShape, "the samples do not fit in a batch of %d", shape[0])
+		}
+		copy(dst[offset:], src)
+		offset += len(src)
+	}
+	return res, nil
+}
+
+// chunkBatch copies the batch entries [start, end) of t, whose entries have the size of the
+// entries of shape, into a new tensor of the given shape. The entries after them are zero.
+// Unlike sliceBatch, the shape of t is ignored, so t can be flattened.
+func chunkBatch(t *gotensor.Dense, shape []int, start, end int) (*gotensor.Dense, error) {
+	if len(shape) == 0 || start < 0 || start > end || end-start > shape[0] {
+		return nil, newError("", ErrInvalidShape, "cannot chunk [%d, %d) into a tensor of shape %v", start, end, shape)
+	}
+	res := gotensor.New(gotensor.Of(t.Dtype()), gotensor.WithShape(shape...))
+	dst := denseBytes(res)
+	if len(dst) == 0 {
+		return res, nil
+	}
+	entryBytes := len(dst) / shape[0]
+	src := denseBytes(t)
+	if end*entryBytes > len(src) {
+		return nil, newError("", ErrInvalidShape, "cannot chunk [%d, %d) out of a tensor of %d elements", start, end, t.Size())
+	}
+	copy(dst, src[start*entryBytes:end*entryBytes])
+	return res, nil
+}
+
+// sliceBatch copies the batch entries [start, end) of t into a new tensor
+func sliceBatch(t *gotensor.Dense, start, end int) (*gotensor.Dense, error) {
+	shape := []int(t.Shape())
+	if len(shape) == 0 || start < 0 || end > shape[0] || start > end {
+		return nil, newError("", ErrInvalidShape, "cannot slice [%d, %d) out of a tensor of shape %v", start, end, shape)
+	}
+	resShape := append([]int{end - start}, shape[1:]...)
+	res := gotensor.New(gotensor.Of(t.Dtype()), gotensor.WithShape(resShape...))
+	if shape[0] == 0 {
+		return res, nil
+	}
+	src := denseBytes(t)
+	entryBytes := len(src) / shape[0]
+	copy(denseBytes(res), src[start*entryBytes:end*entryBytes])
+	return res, nil
+}
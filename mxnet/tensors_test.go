@@ -0,0 +1,190 @@
+package mxnet
+
+import (
+	"reflect"
+	"testing"
+
+	gotensor "gorgonia.org/tensor"
+)
+
+func float32Tensor(shape []int, data ...float32) *gotensor.Dense {
+	return gotensor.New(gotensor.WithShape(shape...), gotensor.WithBacking(data))
+}
+
+func checkTensor(t *testing.T, got *gotensor.Dense, shape []int, data interface{}) {
+	t.Helper()
+	if s := []int(got.Shape()); !reflect.DeepEqual(s, shape) {
+		t.Errorf("got shape %v, expecting %v", s, shape)
+	}
+	if d := got.Data(); !reflect.DeepEqual(d, data) {
+		t.Errorf("got data %v, expecting %v", d, data)
+	}
+}
+
+func TestStackBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		shape   []int
+		samples []*gotensor.Dense
+		data    []float32
+	}{
+		{
+			name:    "one entry per sample",
+			shape:   []int{2, 2},
+			samples: []*gotensor.Dense{float32Tensor([]int{2}, 1, 2), float32Tensor([]int{1, 2}, 3, 4)},
+			data:    []float32{1, 2, 3, 4},
+		},
+		{
+			name:    "several entries per sample",
+			shape:   []int{3, 2},
+			samples: []*gotensor.Dense{float32Tensor([]int{2, 2}, 1, 2, 3, 4), float32Tensor([]int{2}, 5, 6)},
+			data:    []float32{1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:    "zero padded",
+			shape:   []int{3, 2},
+			samples: []*gotensor.Dense{float32Tensor([]int{2}, 1, 2)},
+			data:    []float32{1, 2, 0, 0, 0, 0},
+		},
+		{
+			name:  "no samples",
+			shape: []int{1, 2},
+			data:  []float32{0, 0},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := stackBatch(gotensor.Float32, tc.shape, tc.samples)
+			if err != nil {
+				t.Fatalf("stackBatch: %v", err)
+			}
+			checkTensor(t, res, tc.shape, tc.data)
+		})
+	}
+}
+
+func TestStackBatchErrors(t *testing.T) {
+	int32Sample := gotensor.New(gotensor.WithShape(2), gotensor.WithBacking([]int32{1, 2}))
+	tests := []struct {
+		name    string
+		shape   []int
+		samples []*gotensor.Dense
+	}{
+		{"scalar", []int{}, []*gotensor.Dense{float32Tensor([]int{1}, 1)}},
+		{"dtype", []int{1, 2}, []*gotensor.Dense{int32Sample}},
+		{"partial entry", []int{2, 2}, []*gotensor.Dense{float32Tensor([]int{3}, 1, 2, 3)}},
+		{"overflow", []int{1, 2}, []*gotensor.Dense{float32Tensor([]int{2}, 1, 2), float32Tensor([]int{2}, 3, 4)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := stackBatch(gotensor.Float32, tc.shape, tc.samples); err == nil {
+				t.Fatal("stackBatch should fail")
+			}
+		})
+	}
+}
+
+func TestSliceBatch(t *testing.T) {
+	src := float32Tensor([]int{3, 2}, 1, 2, 3, 4, 5, 6)
+	tests := []struct {
+		name       string
+		start, end int
+		shape      []int
+		data       []float32
+	}{
+		{"first", 0, 1, []int{1, 2}, []float32{1, 2}},
+		{"last two", 1, 3, []int{2, 2}, []float32{3, 4, 5, 6}},
+		{"all", 0, 3, []int{3, 2}, []float32{1, 2, 3, 4, 5, 6}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := sliceBatch(src, tc.start, tc.end)
+			if err != nil {
+				t.Fatalf("sliceBatch: %v", err)
+			}
+			checkTensor(t, res, tc.shape, tc.data)
+		})
+	}
+
+	empty, err := sliceBatch(src, 2, 2)
+	if err != nil {
+		t.Fatalf("sliceBatch: %v", err)
+	}
+	if s := []int(empty.Shape()); !reflect.DeepEqual(s, []int{0, 2}) {
+		t.Errorf("got shape %v for an empty slice, expecting [0 2]", s)
+	}
+
+	for _, r := range [][2]int{{-1, 1}, {2, 1}, {0, 4}} {
+		if _, err := sliceBatch(src, r[0], r[1]); err == nil {
+			t.Errorf("sliceBatch [%d, %d) should fail", r[0], r[1])
+		}
+	}
+}
+
+func TestChunkBatch(t *testing.T) {
+	// chunkBatch ignores the shape of the source, so it is flattened here
+	src := float32Tensor([]int{6}, 1, 2, 3, 4, 5, 6)
+	shape := []int{2, 2}
+	tests := []struct {
+		name       string
+		start, end int
+		data       []float32
+	}{
+		{"full chunk", 0, 2, []float32{1, 2, 3, 4}},
+		{"last chunk zero padded", 2, 3, []float32{5, 6, 0, 0}},
+		{"empty chunk", 1, 1, []float32{0, 0, 0, 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := chunkBatch(src, shape, tc.start, tc.end)
+			if err != nil {
+				t.Fatalf("chunkBatch: %v", err)
+			}
+			checkTensor(t, res, shape, tc.data)
+		})
+	}
+
+	for _, r := range [][2]int{{-1, 1}, {2, 1}, {0, 3}, {2, 4}} {
+		if _, err := chunkBatch(src, shape, r[0], r[1]); err == nil {
+			t.Errorf("chunkBatch [%d, %d) should fail", r[0], r[1])
+		}
+	}
+	if _, err := chunkBatch(src, []int{}, 0, 1); err == nil {
+		t.Error("chunkBatch into a scalar should fail")
+	}
+}
+
+func TestBatchViews(t *testing.T) {
+	src := float32Tensor([]int{3, 3}, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	rows := sliced(t, src, span{1, 3})
+	columns := sliced(t, src, nil, span{1, 3})
+
+	stacked, err := stackBatch(gotensor.Float32, []int{4, 2}, []*gotensor.Dense{columns, sliced(t, src, span{2, 3}, span{0, 2})})
+	if err != nil {
+		t.Fatalf("stackBatch: %v", err)
+	}
+	checkTensor(t, stacked, []int{4, 2}, []float32{2, 3, 5, 6, 8, 9, 7, 8})
+
+	stacked, err = stackBatch(gotensor.Float32, []int{2, 3}, []*gotensor.Dense{rows})
+	if err != nil {
+		t.Fatalf("stackBatch: %v", err)
+	}
+	checkTensor(t, stacked, []int{2, 3}, []float32{4, 5, 6, 7, 8, 9})
+
+	chunk, err := chunkBatch(columns, []int{2, 2}, 1, 3)
+	if err != nil {
+		t.Fatalf("chunkBatch: %v", err)
+	}
+	checkTensor(t, chunk, []int{2, 2}, []float32{5, 6, 8, 9})
+
+	slice, err := sliceBatch(columns, 2, 3)
+	if err != nil {
+		t.Fatalf("sliceBatch: %v", err)
+	}
+	checkTensor(t, slice, []int{1, 2}, []float32{8, 9})
+
+	// the view is read, not the tensor it was taken from
+	if d := src.Data(); !reflect.DeepEqual(d, []float32{1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("the source was modified: %v", d)
+	}
+}
@@ -0,0 +1,107 @@
+package mxnet
+
+import (
+	"github.com/rai-project/dlframework/framework/options"
+	gotensor "gorgonia.org/tensor"
+)
+
+// validateInputs checks the data against the input nodes before it is passed to mxnet.
+// There must be one tensor per input node, with the element count of the node's shape.
+// A tensor with the same rank as the node must have the node's shape, tensors of
+// other ranks (e.g. flattened data) only need the same number of elements.
+// The tensor dtype must be supported and match the node dtype when it is set.
+func validateInputs(nodes []options.Node, data []*gotensor.Dense) error {
+	if len(data) > len(nodes) {
+		return inputError("", ErrInvalidInput, "got %d inputs, but the predictor has %d input nodes", len(data), len(nodes))
+	}
+	for ii, node := range nodes {
+		if node.Key == "" {
+			return inputError("", ErrUnknownInput, "input node %d has no name", ii)
+		}
+		if ii >= len(data) {
+			return inputError(node.Key, ErrInvalidInput, "missing input data")
+		}
+		if err := validateInput(node, data[ii]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateInput(node options.Node, input *gotensor.Dense) error {
+	if err := validateInputDtype(node, input); err != nil {
+		return err
+	}
+	if input.Size() != prod(node.Shape) {
+		return inputError(node.Key, ErrInvalidShape, "got %d elements, expecting %d for shape %v",
+			input.Size(), prod(node.Shape), node.Shape)
+	}
+	shape := input.Shape()
+	if len(shape) != len(node.Shape) {
+		return nil
+	}
+	for ii, dim := range shape {
+		if dim != node.Shape[ii] {
+			return inputError(node.Key, ErrInvalidShape, "got shape %v, expecting %v", shape, node.Shape)
+		}
+	}
+	return nil
+}
+
+func validateInputDtype(node options.Node, input *gotensor.Dense) error {
+	if input == nil {
+		return inputError(node.Key, ErrInvalidInput, "input data is nil")
+	}
+	if !isSupportedDtype(input.Dtype()) {
+		return inputError(node.Key, ErrUnsupportedDtype, "%v", input.Dtype())
+	}
+	if node.Dtype.Type != nil && node.Dtype != input.Dtype() {
+		return inputError(node.Key, ErrUnsupportedDtype, "got dtype %v, expecting %v", input.Dtype(), node.Dtype)
+	}
+	return nil
+}
+
+// validateBatch is validateInputs for any number of samples, a sample being an entry
+// of the leading (batch) dimension of the input nodes. Every tensor must hold the same
+// number of samples, and a tensor with the same rank as its node must have the node's
+// shape apart from the leading dimension.
+// It returns the number of samples and the bound batch size. Both are 0 when the input
+// nodes have no shared batch dimension, the data must then match the nodes exactly.
+func validateBatch(nodes []options.Node, data []*gotensor.Dense) (int, int, error) {
+	batchSize, err := boundBatchSize(nodes)
+	if err != nil {
+		return 0, 0, validateInputs(nodes, data)
+	}
+	if len(data) != len(nodes) {
+		return 0, 0, validateInputs(nodes, data)
+	}
+
+	samples := 0
+	for ii, node := range nodes {
+		input := data[ii]
+		if err := validateInputDtype(node, input); err != nil {
+			return 0, 0, err
+		}
+		sampleSize := prod(node.Shape[1:])
+		if input.Size() == 0 || sampleSize == 0 || input.Size()%sampleSize != 0 {
+			return 0, 0, inputError(node.Key, ErrInvalidShape, "got %d elements, expecting a multiple of %d for samples of shape %v",
+				input.Size(), sampleSize, node.Shape[1:])
+		}
+		shape := input.Shape()
+		if len(shape) == len(node.Shape) {
+			for jj := 1; jj < len(shape); jj++ {
+				if shape[jj] != node.Shape[jj] {
+					return 0, 0, inputError(node.Key, ErrInvalidShape, "got shape %v, expecting samples of shape %v",
+						shape, node.Shape[1:])
+				}
+			}
+		}
+		n := input.Size() / sampleSize
+		if ii > 0 && n != samples {
+			return 0, 0, inputError(node.Key, ErrInvalidShape, "got %d samples, but input %s has %d",
+				n, nodes[0].Key, samples)
+		}
+		samples = n
+	}
+	return samples, batchSize, nil
+}
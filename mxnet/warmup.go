@@ -0,0 +1,67 @@
+package mxnet
+
+import (
+	"context"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rai-project/tracer"
+	gotensor "gorgonia.org/tensor"
+)
+
+// Warmup runs iterations forward passes on zero inputs of the bound input shapes,
+// reading the outputs back each time, and returns the latency of each iteration.
+// The first forward passes after New are slower because of memory planning and
+// first touch allocations; the latencies tell when the predictor has settled.
+// Each iteration holds the predictor from setting the inputs to reading the outputs,
+// as Benchmark does, so concurrent callers cannot run in between.
+func (p *Predictor) Warmup(ctx context.Context, iterations int) ([]time.Duration, error) {
+	if iterations <= 0 {
+		return nil, newError("", ErrInvalidOptions, "invalid number of warmup iterations %d", iterations)
+	}
+
+	span, ctx := tracer.StartSpanFromContext(ctx, tracer.MODEL_TRACE, "c_warmup",
+		opentracing.Tags{
+			"iterations": iterations,
+		})
+	defer span.Finish()
+
+	inputs := p.zeroInputs()
+
+	latencies := make([]time.Duration, 0, iterations)
+	for ii := 0; ii < iterations; ii++ {
+		start := time.Now()
+		if err := p.warmupIteration(ctx, inputs); err != nil {
+			return latencies, err
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	return latencies, nil
+}
+
+// warmupIteration sets the inputs, runs a forward pass and reads the outputs under one lock
+func (p *Predictor) warmupIteration(ctx context.Context, inputs []*gotensor.Dense) error {
+	if err := p.lockContext(ctx); err != nil {
+		return err
+	}
+	defer p.mu.Unlock()
+
+	if err := p.predictBatch(ctx, inputs); err != nil {
+		return err
+	}
+	_, err := p.readPredictionOutputs(ctx)
+	return err
+}
+
+// zeroInputs creates a zero tensor for each input node of the predictor
+func (p *Predictor) zeroInputs() []*gotensor.Dense {
+	nodes := p.GetOptions().InputNodes()
+	inputs := make([]*gotensor.Dense, len(nodes))
+	for ii, nd := range nodes {
+		inputs[ii] = gotensor.New(
+			gotensor.Of(nodeDtype(nd)),
+			gotensor.WithShape(nd.Shape...),
+		)
+	}
+	return inputs
+}
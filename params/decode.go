@@ -0,0 +1,469 @@
+package params
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/rai-project/go-mxnet/utils"
+	gotensor "gorgonia.org/tensor"
+)
+
+// ReadFile decodes the params file at path, see Read
+func ReadFile(path string) ([]Array, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+// Decode decodes the content of a params file, see Read
+func Decode(b []byte) ([]Array, error) {
+	return Read(bytes.NewReader(b))
+}
+
+// Read decodes a params file: the list header, the arrays, and their names.
+// The arrays are returned in the order of the file, as tensors of their dtype.
+// The counts and sizes read from a reader that does not tell its length (see
+// bytes.Reader.Len) are not trusted: the memory allocated is bounded by the input read.
+// Arrays saved in the V1, V2 and V3 formats and in the legacy format (without a magic
+// number) can be read, arrays with a sparse storage type cannot.
+func Read(r io.Reader) ([]Array, error) {
+	d := &decoder{r: r}
+
+	count, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+	arrays := make([]Array, 0, minInt(count, maxPrealloc))
+	for ii := 0; ii < count; ii++ {
+		array, err := d.array()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read array %d", ii)
+		}
+		arrays = append(arrays, array)
+	}
+
+	names, err := d.names(count)
+	if err != nil {
+		return nil, err
+	}
+	for ii, name := range names {
+		arrays[ii].Name = name
+	}
+	return arrays, nil
+}
+
+// Names returns the names of the arrays of a params file, see ReadNames
+func Names(b []byte) ([]string, error) {
+	return ReadNames(bytes.NewReader(b))
+}
+
+// ReadNames reads the names of the arrays of a params file, in the order of the file,
+// without decoding the arrays: their data is skipped. Unlike Read, it accepts arrays
+// with a sparse storage type. The names are empty if the file has no names.
+func ReadNames(r io.Reader) ([]string, error) {
+	d := &decoder{r: r}
+
+	count, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+	for ii := 0; ii < count; ii++ {
+		if err := d.skipArray(); err != nil {
+			return nil, errors.Wrapf(err, "cannot read array %d", ii)
+		}
+	}
+
+	names, err := d.names(count)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		names = make([]string, count)
+	}
+	return names, nil
+}
+
+// maxPrealloc is the number of arrays allocated up front, before they are read
+const maxPrealloc = 1024
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// decoder reads little endian values, and keeps the first error
+type decoder struct {
+	r   io.Reader
+	err error
+}
+
+// header reads the list header and returns the number of arrays
+func (d *decoder) header() (int, error) {
+	magic := d.uint64()
+	d.uint64() // reserved
+	if d.err != nil {
+		return 0, errors.Wrap(d.err, "cannot read the params header")
+	}
+	if magic != listMagic {
+		return 0, errors.Errorf("invalid params magic number %#x, expecting %#x", magic, listMagic)
+	}
+
+	count := d.count(4)
+	if d.err != nil {
+		return 0, errors.Wrap(d.err, "cannot read the number of arrays")
+	}
+	return count, nil
+}
+
+// names reads the names that follow the count arrays, there are none if the file
+// has no names
+func (d *decoder) names(count int) ([]string, error) {
+	n := d.count(8)
+	if d.err != nil {
+		return nil, errors.Wrap(d.err, "cannot read the number of names")
+	}
+	if n != 0 && n != count {
+		return nil, errors.Errorf("the params have %d names for %d arrays", n, count)
+	}
+	names := make([]string, n)
+	for ii := range names {
+		name := d.bytes(d.count(1))
+		if d.err != nil {
+			return nil, errors.Wrapf(d.err, "cannot read the name of array %d", ii)
+		}
+		names[ii] = string(name)
+	}
+	return names, nil
+}
+
+func (d *decoder) read(v interface{}) {
+	if d.err != nil {
+		return
+	}
+	d.err = binary.Read(d.r, binary.LittleEndian, v)
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+}
+
+func (d *decoder) uint32() uint32 {
+	var v uint32
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) int32() int32 {
+	var v int32
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	var v uint64
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) int64() int64 {
+	var v int64
+	d.read(&v)
+	return v
+}
+
+// bytes reads the next n bytes. When the length of the input is unknown, n was not
+// checked by count, and the bytes are read incrementally so that a corrupted size
+// fails at the end of the input instead of allocating n bytes up front.
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if d.remaining() < 0 {
+		var b []byte
+		b, d.err = ioutil.ReadAll(io.LimitReader(d.r, int64(n)))
+		if d.err == nil && len(b) < n {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return b
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+	return b
+}
+
+// skip discards the next n bytes
+func (d *decoder) skip(n int64) {
+	if d.err != nil {
+		return
+	}
+	if rem := d.remaining(); rem >= 0 && n > rem {
+		d.err = io.ErrUnexpectedEOF
+		return
+	}
+	if s, ok := d.r.(io.Seeker); ok {
+		_, d.err = s.Seek(n, io.SeekCurrent)
+		return
+	}
+	_, d.err = io.CopyN(ioutil.Discard, d.r, n)
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+}
+
+// remaining is the number of bytes left, or -1 if the reader does not tell
+func (d *decoder) remaining() int64 {
+	if l, ok := d.r.(interface{ Len() int }); ok {
+		return int64(l.Len())
+	}
+	return -1
+}
+
+// count reads a uint64 count of items of at least itemSize bytes each,
+// and checks that they can fit in the rest of the input
+func (d *decoder) count(itemSize int64) int {
+	n := d.uint64()
+	if d.err != nil {
+		return 0
+	}
+	if rem := d.remaining(); n > 1<<40 || (rem >= 0 && int64(n)*itemSize > rem) {
+		d.err = errors.Errorf("invalid count %d", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) array() (Array, error) {
+	magic := d.uint32()
+
+	var (
+		shape []int
+		known bool
+	)
+	switch magic {
+	case ndarrayV2Magic, ndarrayV3Magic:
+		stype := d.int32()
+		if d.err == nil && stype != storageDefault {
+			return Array{}, errors.Errorf("storage type %d is not supported, only dense arrays are", stype)
+		}
+		shape, known = d.shape(magic)
+	case ndarrayV1Magic:
+		shape, known = d.shape(magic)
+	default:
+		shape, known = d.legacyShape(magic)
+	}
+	if d.err != nil {
+		return Array{}, d.err
+	}
+	if !known {
+		return Array{}, nil
+	}
+
+	ctx := Context{DevType: d.int32(), DevID: d.int32()}
+	flag := utils.TypeFlag(d.int32())
+	if d.err != nil {
+		return Array{}, d.err
+	}
+	dtype, err := flag.Dtype()
+	if err != nil {
+		return Array{}, err
+	}
+
+	size := int64(1)
+	for _, dim := range shape {
+		size *= int64(dim)
+		if size > 1<<40 {
+			return Array{}, errors.Errorf("invalid shape %v", shape)
+		}
+	}
+	if rem := d.remaining(); rem >= 0 && size*int64(dtype.Size()) > rem {
+		return Array{}, errors.Errorf("the data of shape %v does not fit in the %d remaining bytes", shape, rem)
+	}
+
+	data := d.data(dtype, int(size))
+	if d.err != nil {
+		return Array{}, d.err
+	}
+	return Array{
+		Context: ctx,
+		Tensor: gotensor.New(
+			gotensor.Of(dtype),
+			gotensor.WithShape(shape...),
+			gotensor.WithBacking(data),
+		),
+	}, nil
+}
+
+// skipArray reads the header of an array and skips its data, it accepts every
+// storage type. A sparse array stores its storage shape before its shape, and the
+// types and shapes of its aux arrays (the indices) after its type; its data is
+// followed by the data of its aux arrays.
+func (d *decoder) skipArray() error {
+	magic := d.uint32()
+
+	var (
+		shape, storageShape []int
+		known               bool
+		numAux              int
+	)
+	switch magic {
+	case ndarrayV2Magic, ndarrayV3Magic:
+		stype := d.int32()
+		if d.err != nil {
+			return d.err
+		}
+		switch stype {
+		case storageDefault:
+		case storageRowSparse:
+			numAux = 1
+		case storageCSR:
+			numAux = 2
+		default:
+			return errors.Errorf("invalid storage type %d", stype)
+		}
+		if numAux != 0 {
+			storageShape, _ = d.shape(magic)
+		}
+		shape, known = d.shape(magic)
+	case ndarrayV1Magic:
+		shape, known = d.shape(magic)
+	default:
+		shape, known = d.legacyShape(magic)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if !known {
+		return nil
+	}
+
+	d.int32() // device type
+	d.int32() // device id
+	flag := d.int32()
+	auxFlags := make([]int32, numAux)
+	for ii := range auxFlags {
+		auxFlags[ii] = d.int32()
+	}
+	auxShapes := make([][]int, numAux)
+	for ii := range auxShapes {
+		auxShapes[ii], _ = d.shape(magic)
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	if numAux != 0 {
+		shape = storageShape
+	}
+	n, err := dataBytes(flag, shape)
+	if err != nil {
+		return err
+	}
+	d.skip(n)
+	for ii, auxShape := range auxShapes {
+		n, err := dataBytes(auxFlags[ii], auxShape)
+		if err != nil {
+			return err
+		}
+		d.skip(n)
+	}
+	return d.err
+}
+
+// dataBytes is the size of the data of an array of type flag and shape
+func dataBytes(flag int32, shape []int) (int64, error) {
+	dtype, err := utils.TypeFlag(flag).Dtype()
+	if err != nil {
+		return 0, err
+	}
+	size := int64(dtype.Size())
+	for _, dim := range shape {
+		size *= int64(dim)
+		if size > 1<<40 {
+			return 0, errors.Errorf("invalid shape %v", shape)
+		}
+	}
+	return size, nil
+}
+
+// legacyShape reads the uint32 dimensions of a legacy array, which starts with its ndim
+func (d *decoder) legacyShape(ndim uint32) (shape []int, known bool) {
+	if ndim > 32 {
+		d.err = errors.Errorf("invalid ndarray magic number or ndim %#x", ndim)
+		return nil, false
+	}
+	shape = make([]int, ndim)
+	for ii := range shape {
+		shape[ii] = int(d.uint32())
+	}
+	return shape, len(shape) != 0
+}
+
+// shape reads the shape of a V1, V2 or V3 array, known is false for an array without
+// a shape (ndim 0 before V3, and an unknown ndim or dimension in V3)
+func (d *decoder) shape(magic uint32) (shape []int, known bool) {
+	ndim := d.int32()
+	if d.err != nil {
+		return nil, false
+	}
+	if magic == ndarrayV3Magic && ndim == -1 {
+		return nil, false
+	}
+	if ndim < 0 || ndim > 32 {
+		d.err = errors.Errorf("invalid ndim %d", ndim)
+		return nil, false
+	}
+	known = ndim != 0 || magic == ndarrayV3Magic
+	shape = make([]int, ndim)
+	for ii := range shape {
+		dim := d.int64()
+		if dim < 0 {
+			if magic == ndarrayV3Magic && dim == -1 {
+				known = false
+				continue
+			}
+			d.err = errors.Errorf("invalid dimension %d", dim)
+			return nil, false
+		}
+		shape[ii] = int(dim)
+	}
+	return shape, known
+}
+
+// data reads size values of dtype into a slice that backs a tensor.
+// When the length of the input is unknown, the raw bytes are read first, see bytes,
+// and the values are only allocated once they were all read.
+func (d *decoder) data(dtype gotensor.Dtype, size int) interface{} {
+	src := d
+	if d.remaining() < 0 {
+		raw := d.bytes(size * int(dtype.Size()))
+		if d.err != nil {
+			return nil
+		}
+		src = &decoder{r: bytes.NewReader(raw)}
+	}
+
+	if dtype == utils.Float16Dtype {
+		bits := make([]uint16, size)
+		src.read(bits)
+		res := make([]utils.Float16, size)
+		for ii, b := range bits {
+			res[ii] = utils.Float16(b)
+		}
+		d.err = src.err
+		return res
+	}
+	data := reflect.MakeSlice(reflect.SliceOf(dtype.Type), size, size).Interface()
+	src.read(data)
+	d.err = src.err
+	return data
+}
@@ -0,0 +1,207 @@
+package params
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/rai-project/go-mxnet/utils"
+)
+
+// le encodes the values in little endian, as in a params file
+func le(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// paramsFile builds a params file holding the raw arrays and their names
+func paramsFile(arrays [][]byte, names ...string) []byte {
+	b := le(listMagic, uint64(0), uint64(len(arrays)))
+	for _, a := range arrays {
+		b = append(b, a...)
+	}
+	b = append(b, le(uint64(len(names)))...)
+	for _, name := range names {
+		b = append(b, le(uint64(len(name)))...)
+		b = append(b, name...)
+	}
+	return b
+}
+
+// onlyReader hides the Len method of a bytes.Reader
+type onlyReader struct {
+	io.Reader
+}
+
+var (
+	float32Flag = int32(utils.TypeFloat32)
+	int32Flag   = int32(utils.TypeInt32)
+	float16Flag = int32(utils.TypeFloat16)
+	int64Flag   = int32(utils.TypeInt64)
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		array []byte
+		shape []int
+		ctx   Context
+		data  interface{}
+	}{
+		{
+			name:  "v2",
+			array: le(ndarrayV2Magic, storageDefault, int32(2), []int64{2, 1}, int32(1), int32(0), float32Flag, []float32{1, 2}),
+			shape: []int{2, 1},
+			ctx:   CPU,
+			data:  []float32{1, 2},
+		},
+		{
+			name:  "v3",
+			array: le(ndarrayV3Magic, storageDefault, int32(1), []int64{2}, int32(2), int32(1), int32Flag, []int32{-1, 7}),
+			shape: []int{2},
+			ctx:   Context{DevType: 2, DevID: 1},
+			data:  []int32{-1, 7},
+		},
+		{
+			name:  "v1",
+			array: le(ndarrayV1Magic, int32(1), []int64{3}, int32(1), int32(0), float16Flag, []uint16{0x3c00, 0xc000, 0}),
+			shape: []int{3},
+			ctx:   CPU,
+			data:  []utils.Float16{0x3c00, 0xc000, 0},
+		},
+		{
+			name:  "legacy",
+			array: le(uint32(2), []uint32{1, 2}, int32(1), int32(0), float32Flag, []float32{3, 4}),
+			shape: []int{1, 2},
+			ctx:   CPU,
+			data:  []float32{3, 4},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := paramsFile([][]byte{tc.array}, "arg:x")
+			for _, r := range []io.Reader{bytes.NewReader(b), onlyReader{bytes.NewReader(b)}} {
+				arrays, err := Read(r)
+				if err != nil {
+					t.Fatalf("Read: %v", err)
+				}
+				if len(arrays) != 1 {
+					t.Fatalf("got %d arrays, expecting 1", len(arrays))
+				}
+				a := arrays[0]
+				if a.Name != "arg:x" || a.Key() != "x" {
+					t.Errorf("got name %q and key %q", a.Name, a.Key())
+				}
+				if a.Context != tc.ctx {
+					t.Errorf("got context %v, expecting %v", a.Context, tc.ctx)
+				}
+				if shape := []int(a.Tensor.Shape()); !reflect.DeepEqual(shape, tc.shape) {
+					t.Errorf("got shape %v, expecting %v", shape, tc.shape)
+				}
+				if data := a.Tensor.Data(); !reflect.DeepEqual(data, tc.data) {
+					t.Errorf("got data %v, expecting %v", data, tc.data)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeWithoutShape(t *testing.T) {
+	arrays := [][]byte{
+		le(ndarrayV2Magic, storageDefault, int32(0)),
+		le(ndarrayV3Magic, storageDefault, int32(-1)),
+		le(ndarrayV3Magic, storageDefault, int32(2), []int64{2, -1}),
+		le(uint32(0)),
+	}
+	res, err := Decode(paramsFile(arrays))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(res) != len(arrays) {
+		t.Fatalf("got %d arrays, expecting %d", len(res), len(arrays))
+	}
+	for ii, a := range res {
+		if a.Tensor != nil || a.Name != "" {
+			t.Errorf("array %d: got %+v, expecting an unnamed array without a tensor", ii, a)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := le(ndarrayV2Magic, storageDefault, int32(1), []int64{2}, int32(1), int32(0), float32Flag, []float32{1, 2})
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"magic", le(uint64(0x113), uint64(0), uint64(0), uint64(0))},
+		{"truncated header", le(listMagic)},
+		{"truncated data", paramsFile([][]byte{valid[:len(valid)-1]})},
+		{"sparse", paramsFile([][]byte{le(ndarrayV2Magic, storageRowSparse)})},
+		{"ndim", paramsFile([][]byte{le(ndarrayV2Magic, storageDefault, int32(33))})},
+		{"legacy ndim", paramsFile([][]byte{le(uint32(33))})},
+		{"dimension", paramsFile([][]byte{le(ndarrayV2Magic, storageDefault, int32(1), int64(-1))})},
+		{"type flag", paramsFile([][]byte{le(ndarrayV2Magic, storageDefault, int32(1), int64(1), int32(1), int32(0), int32(99))})},
+		{"names count", paramsFile([][]byte{valid}, "arg:a", "arg:b")},
+		{"array count", le(listMagic, uint64(0), uint64(1<<39))},
+		{"huge shape", paramsFile([][]byte{le(ndarrayV2Magic, storageDefault, int32(2), []int64{1 << 20, 1 << 20}, int32(1), int32(0), float32Flag)})},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Decode(tc.b); err == nil {
+				t.Error("Decode should fail")
+			}
+			// without a length, the counts and sizes cannot be checked up front
+			if _, err := Read(onlyReader{bytes.NewReader(tc.b)}); err == nil {
+				t.Error("Read should fail")
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	arrays := [][]byte{
+		le(ndarrayV2Magic, storageDefault, int32(1), []int64{2}, int32(1), int32(0), float32Flag, []float32{1, 2}),
+		// row sparse: storage shape, shape, context, type, aux type, aux shape, data, aux data
+		le(ndarrayV2Magic, storageRowSparse, int32(2), []int64{1, 2}, int32(2), []int64{4, 2},
+			int32(1), int32(0), float32Flag, int64Flag, int32(1), []int64{1},
+			[]float32{1, 2}, []int64{3}),
+		// csr: two aux arrays, the row pointers and the column indices
+		le(ndarrayV2Magic, storageCSR, int32(1), []int64{2}, int32(2), []int64{2, 3},
+			int32(1), int32(0), float32Flag, int64Flag, int64Flag, int32(1), []int64{3}, int32(1), []int64{2},
+			[]float32{1, 2}, []int64{0, 1, 2}, []int64{0, 2}),
+		le(ndarrayV3Magic, storageDefault, int32(-1)),
+		le(uint32(1), uint32(1), int32(1), int32(0), float32Flag, float32(1)),
+	}
+	names := []string{"arg:dense", "arg:row_sparse", "arg:csr", "aux:unknown", "aux:legacy"}
+
+	b := paramsFile(arrays, names...)
+	for _, r := range []io.Reader{bytes.NewReader(b), onlyReader{bytes.NewReader(b)}} {
+		got, err := ReadNames(r)
+		if err != nil {
+			t.Fatalf("ReadNames: %v", err)
+		}
+		if !reflect.DeepEqual(got, names) {
+			t.Errorf("got names %v, expecting %v", got, names)
+		}
+	}
+
+	got, err := Names(paramsFile(arrays))
+	if err != nil {
+		t.Fatalf("Names: %v", err)
+	}
+	if !reflect.DeepEqual(got, make([]string, len(arrays))) {
+		t.Errorf("got names %q for an unnamed file, expecting empty names", got)
+	}
+
+	if _, err := Names(b[:len(b)-1]); err == nil {
+		t.Error("Names of a truncated file should fail")
+	}
+}
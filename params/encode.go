@@ -0,0 +1,151 @@
+package params
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rai-project/go-mxnet/utils"
+	gotensor "gorgonia.org/tensor"
+)
+
+// Named returns the arrays of tensors sorted by name, ready for Write
+func Named(tensors map[string]*gotensor.Dense) []Array {
+	arrays := make([]Array, 0, len(tensors))
+	for name, t := range tensors {
+		arrays = append(arrays, Array{Name: name, Context: CPU, Tensor: t})
+	}
+	sort.Slice(arrays, func(i, j int) bool {
+		return arrays[i].Name < arrays[j].Name
+	})
+	return arrays
+}
+
+// WriteFile writes the arrays to a params file at path, see Write
+func WriteFile(path string, arrays []Array) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := Write(w, arrays); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Encode returns the content of a params file holding the arrays, see Write
+func Encode(arrays []Array) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, arrays); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write writes the arrays in the params format loaded by MXPredCreate and MXNDListCreate:
+// dense V2 arrays saved from the cpu, whatever their Context, followed by their names.
+// Every array must be named with an arg: or aux: prefix, and the names must be unique.
+// An array with a nil tensor is written without a shape; scalars cannot be written.
+func Write(w io.Writer, arrays []Array) error {
+	seen := map[string]bool{}
+	for _, a := range arrays {
+		if kind, _ := SplitName(a.Name); kind == "" {
+			return errors.Errorf("array %q has no arg: or aux: prefix", a.Name)
+		}
+		if seen[a.Name] {
+			return errors.Errorf("duplicate array %q", a.Name)
+		}
+		seen[a.Name] = true
+	}
+
+	e := &encoder{w: w}
+	e.write(listMagic)
+	e.write(uint64(0)) // reserved
+	e.write(uint64(len(arrays)))
+	for _, a := range arrays {
+		if err := e.array(a.Tensor); err != nil {
+			return errors.Wrapf(err, "cannot write array %s", a.Name)
+		}
+	}
+	e.write(uint64(len(arrays)))
+	for _, a := range arrays {
+		e.write(uint64(len(a.Name)))
+		e.write([]byte(a.Name))
+	}
+	return e.err
+}
+
+// encoder writes little endian values, and keeps the first error
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) write(v interface{}) {
+	if e.err != nil {
+		return
+	}
+	e.err = binary.Write(e.w, binary.LittleEndian, v)
+}
+
+func (e *encoder) array(t *gotensor.Dense) error {
+	e.write(ndarrayV2Magic)
+	e.write(storageDefault)
+	if t == nil {
+		e.write(uint32(0))
+		return e.err
+	}
+
+	shape := t.Shape()
+	if len(shape) == 0 {
+		return errors.New("scalars cannot be saved in the V2 format")
+	}
+	flag, err := utils.TypeFlagOf(t.Dtype())
+	if err != nil {
+		return err
+	}
+	data, err := tensorData(t)
+	if err != nil {
+		return err
+	}
+
+	e.write(uint32(len(shape)))
+	for _, dim := range shape {
+		e.write(int64(dim))
+	}
+	e.write(CPU.DevType)
+	e.write(CPU.DevID)
+	e.write(int32(flag))
+	e.write(data)
+	return e.err
+}
+
+// tensorData returns the backing slice of t, with float16 values as their bits
+func tensorData(t *gotensor.Dense) (interface{}, error) {
+	if t.IsView() {
+		return nil, errors.New("views cannot be saved, materialize the tensor first")
+	}
+	data := t.Data()
+	if f16, ok := data.([]utils.Float16); ok {
+		bits := make([]uint16, len(f16))
+		for ii, v := range f16 {
+			bits[ii] = uint16(v)
+		}
+		data = bits
+	}
+	if n := reflect.ValueOf(data).Len(); n != t.Size() {
+		return nil, errors.Errorf("the tensor has %d elements, but its backing has %d", t.Size(), n)
+	}
+	return data, nil
+}
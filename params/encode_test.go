@@ -0,0 +1,156 @@
+package params
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rai-project/go-mxnet/utils"
+	gotensor "gorgonia.org/tensor"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		arrays []Array
+	}{
+		{
+			name: "float32",
+			arrays: []Array{
+				{Name: "arg:fc_weight", Tensor: gotensor.New(gotensor.WithShape(2, 3), gotensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}))},
+				{Name: "arg:fc_bias", Tensor: gotensor.New(gotensor.WithShape(2), gotensor.WithBacking([]float32{-1, 1}))},
+			},
+		},
+		{
+			name: "dtypes",
+			arrays: []Array{
+				{Name: "arg:f64", Tensor: gotensor.New(gotensor.WithShape(1), gotensor.WithBacking([]float64{0.5}))},
+				{Name: "arg:f16", Tensor: gotensor.New(gotensor.Of(utils.Float16Dtype), gotensor.WithShape(2), gotensor.WithBacking([]utils.Float16{0x3c00, 0x7bff}))},
+				{Name: "arg:u8", Tensor: gotensor.New(gotensor.WithShape(3), gotensor.WithBacking([]uint8{0, 128, 255}))},
+				{Name: "aux:i32", Tensor: gotensor.New(gotensor.WithShape(1, 1), gotensor.WithBacking([]int32{-7}))},
+				{Name: "aux:i8", Tensor: gotensor.New(gotensor.WithShape(2), gotensor.WithBacking([]int8{-128, 127}))},
+				{Name: "aux:i64", Tensor: gotensor.New(gotensor.WithShape(1), gotensor.WithBacking([]int64{1 << 40}))},
+			},
+		},
+		{
+			name:   "without shape",
+			arrays: []Array{{Name: "aux:empty"}},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := Encode(tc.arrays)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got, err := Decode(b)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			checkArrays(t, got, tc.arrays)
+
+			names, err := Names(b)
+			if err != nil {
+				t.Fatalf("Names: %v", err)
+			}
+			for ii, a := range tc.arrays {
+				if names[ii] != a.Name {
+					t.Errorf("name %d is %q, expecting %q", ii, names[ii], a.Name)
+				}
+			}
+		})
+	}
+}
+
+// checkArrays checks that the decoded arrays match the written ones, saved from the cpu
+func checkArrays(t *testing.T, got, want []Array) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d arrays, expecting %d", len(got), len(want))
+	}
+	for ii, a := range got {
+		w := want[ii]
+		if a.Name != w.Name {
+			t.Errorf("array %d is named %q, expecting %q", ii, a.Name, w.Name)
+		}
+		if w.Tensor == nil {
+			if a.Tensor != nil {
+				t.Errorf("array %s has a tensor, expecting none", w.Name)
+			}
+			continue
+		}
+		if a.Context != CPU {
+			t.Errorf("array %s has context %v, expecting %v", w.Name, a.Context, CPU)
+		}
+		if a.Tensor.Dtype() != w.Tensor.Dtype() {
+			t.Errorf("array %s has dtype %v, expecting %v", w.Name, a.Tensor.Dtype(), w.Tensor.Dtype())
+		}
+		if !a.Tensor.Shape().Eq(w.Tensor.Shape()) {
+			t.Errorf("array %s has shape %v, expecting %v", w.Name, a.Tensor.Shape(), w.Tensor.Shape())
+		}
+		if !reflect.DeepEqual(a.Tensor.Data(), w.Tensor.Data()) {
+			t.Errorf("array %s has data %v, expecting %v", w.Name, a.Tensor.Data(), w.Tensor.Data())
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	arrays := Named(map[string]*gotensor.Dense{
+		"arg:b": gotensor.New(gotensor.WithShape(1), gotensor.WithBacking([]float32{2})),
+		"arg:a": gotensor.New(gotensor.WithShape(1), gotensor.WithBacking([]float32{1})),
+	})
+	if arrays[0].Name != "arg:a" || arrays[1].Name != "arg:b" {
+		t.Fatalf("Named did not sort the arrays: %s, %s", arrays[0].Name, arrays[1].Name)
+	}
+
+	path := filepath.Join(t.TempDir(), "model-0000.params")
+	if err := WriteFile(path, arrays); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	checkArrays(t, got, arrays)
+}
+
+// rows slices the rows [start, end) of a tensor
+type rows struct {
+	start, end int
+}
+
+func (r rows) Start() int { return r.start }
+func (r rows) End() int   { return r.end }
+func (r rows) Step() int  { return 1 }
+
+func TestWriteErrors(t *testing.T) {
+	scalar := gotensor.New(gotensor.FromScalar(float32(1)))
+	view, err := gotensor.New(gotensor.WithShape(2, 2), gotensor.WithBacking([]float32{1, 2, 3, 4})).Slice(rows{0, 1})
+	if err != nil {
+		t.Fatalf("Slice: %v", err)
+	}
+	one := gotensor.New(gotensor.WithShape(1), gotensor.WithBacking([]float32{1}))
+
+	tests := []struct {
+		name   string
+		arrays []Array
+	}{
+		{"no prefix", []Array{{Name: "weight", Tensor: one}}},
+		{"duplicate", []Array{{Name: "arg:w", Tensor: one}, {Name: "arg:w", Tensor: one}}},
+		{"scalar", []Array{{Name: "arg:s", Tensor: scalar}}},
+		{"view", []Array{{Name: "arg:v", Tensor: view.(*gotensor.Dense)}}},
+		{"dtype", []Array{{Name: "arg:c", Tensor: gotensor.New(gotensor.WithShape(1), gotensor.WithBacking([]complex64{1}))}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tc.arrays); err == nil {
+				t.Error("Write should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Package params reads and writes MXNet NDArray files (.params) without libmxnet.
+// A file holds a list of arrays, each optionally named; the arrays of a model are
+// named after their symbol arguments, with an arg: or aux: prefix.
+package params
+
+import (
+	"strings"
+
+	gotensor "gorgonia.org/tensor"
+)
+
+// magic numbers of the NDArray file format (src/ndarray/ndarray.cc)
+const (
+	listMagic = uint64(0x112)
+	// arrays saved without a magic number start with their uint32 ndim,
+	// and have uint32 dimensions
+	ndarrayV1Magic = uint32(0xF993fac8)
+	// adds the storage type
+	ndarrayV2Magic = uint32(0xF993fac9)
+	// numpy shape semantics, the ndim is -1 for an unknown shape
+	ndarrayV3Magic = uint32(0xF993faca)
+)
+
+// storage types of an ndarray, only the dense storage is supported
+const (
+	storageDefault   = int32(0)
+	storageRowSparse = int32(1)
+	storageCSR       = int32(2)
+)
+
+// Context is the device an array was saved from
+type Context struct {
+	DevType int32 // 1 for cpu, 2 for gpu, 3 for cpu pinned memory
+	DevID   int32
+}
+
+// CPU is the context of the arrays written by Encode
+var CPU = Context{DevType: 1, DevID: 0}
+
+// Array is an ndarray of a params file
+type Array struct {
+	// Name as stored, e.g. arg:conv0_weight, empty if the file has no names
+	Name    string
+	Context Context
+	// Tensor has the dtype of the array, it is nil for an array saved without a shape
+	Tensor *gotensor.Dense
+}
+
+// Key is the name of the array without its arg: or aux: prefix
+func (a Array) Key() string {
+	_, key := SplitName(a.Name)
+	return key
+}
+
+// SplitName splits an array name into its arg or aux kind and its key.
+// The kind is empty if the name has no prefix.
+func SplitName(name string) (kind, key string) {
+	for _, prefix := range []string{"arg", "aux"} {
+		if strings.HasPrefix(name, prefix+":") {
+			return prefix, name[len(prefix)+1:]
+		}
+	}
+	return "", name
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+
+	gotensor "gorgonia.org/tensor"
+)
+
+// mxnet type flag of an ndarray, as stored in the __dtype__ attribute of a
+// symbol and in ndarray files
+type TypeFlag int32
+
+// mxnet type flags (mshadow::TypeFlag)
+const (
+	TypeFloat32 TypeFlag = 0
+	TypeFloat64 TypeFlag = 1
+	TypeFloat16 TypeFlag = 2
+	TypeUint8   TypeFlag = 3
+	TypeInt32   TypeFlag = 4
+	TypeInt8    TypeFlag = 5
+	TypeInt64   TypeFlag = 6
+)
+
+var typeFlagDtypes = map[TypeFlag]gotensor.Dtype{
+	TypeFloat32: gotensor.Float32,
+	TypeFloat64: gotensor.Float64,
+	TypeFloat16: Float16Dtype,
+	TypeUint8:   gotensor.Uint8,
+	TypeInt32:   gotensor.Int32,
+	TypeInt8:    gotensor.Int8,
+	TypeInt64:   gotensor.Int64,
+}
+
+// tensor dtype of the type flag
+func (f TypeFlag) Dtype() (gotensor.Dtype, error) {
+	dt, ok := typeFlagDtypes[f]
+	if !ok {
+		return gotensor.Dtype{}, fmt.Errorf("unknown mxnet type flag %d", int32(f))
+	}
+	return dt, nil
+}
+
+// mxnet type flag of the tensor dtype
+func TypeFlagOf(dt gotensor.Dtype) (TypeFlag, error) {
+	for f, d := range typeFlagDtypes {
+		if d == dt {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("dtype %v has no mxnet type flag", dt)
+}
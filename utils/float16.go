@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+
+	gotensor "gorgonia.org/tensor"
+)
+
+// IEEE 754 half precision floating point number
+type Float16 uint16
+
+// tensor dtype for Float16 tensors
+var Float16Dtype = gotensor.Dtype{Type: reflect.TypeOf(Float16(0))}
+
+// convert a float32 to the nearest Float16
+// values out of range become infinity, and NaN stays NaN
+func Float16FromFloat32(f float32) Float16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		// inf or nan
+		if mant != 0 {
+			return Float16(sign | 0x7e00)
+		}
+		return Float16(sign | 0x7c00)
+	case exp-127+15 >= 0x1f:
+		// overflow
+		return Float16(sign | 0x7c00)
+	case exp-127+15 <= 0:
+		// subnormal or zero
+		shift := uint32(14 - (exp - 127 + 15))
+		if shift > 24 {
+			return Float16(sign)
+		}
+		mant |= 0x800000
+		half := mant >> shift
+		// round to nearest even
+		rem := mant & (1<<shift - 1)
+		mid := uint32(1) << (shift - 1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return Float16(sign | uint16(half))
+	}
+
+	half := uint32(exp-127+15)<<10 | mant>>13
+	// round to nearest even, a carry into the exponent is fine
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return Float16(uint32(sign) | half)
+}
+
+// convert a Float16 to float32, this is exact
+func (h Float16) Float32() float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// subnormal, normalize it
+		exp = 127 - 15 + 1
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | exp<<23 | mant<<13)
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat16FromFloat32(t *testing.T) {
+	tests := []struct {
+		name string
+		in   float32
+		want Float16
+	}{
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"one", 1, 0x3c00},
+		{"minus two", -2, 0xc000},
+		{"one third", 1.0 / 3, 0x3555},
+		{"tenth", 0.1, 0x2e66},
+		{"max", 65504, 0x7bff},
+		{"rounds to max", 65519, 0x7bff},
+		{"rounds to infinity", 65520, 0x7c00},
+		{"overflow", 1e6, 0x7c00},
+		{"negative overflow", -1e6, 0xfc00},
+		{"smallest normal", float32(math.Ldexp(1, -14)), 0x0400},
+		{"smallest subnormal", float32(math.Ldexp(1, -24)), 0x0001},
+		{"largest subnormal", float32(math.Ldexp(1023, -24)), 0x03ff},
+		{"underflow", float32(math.Ldexp(1, -26)), 0x0000},
+		{"tie to even down", 1 + float32(math.Ldexp(1, -11)), 0x3c00},
+		{"tie to even up", 1 + float32(math.Ldexp(3, -11)), 0x3c02},
+		{"infinity", float32(math.Inf(1)), 0x7c00},
+		{"negative infinity", float32(math.Inf(-1)), 0xfc00},
+		{"nan", float32(math.NaN()), 0x7e00},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Float16FromFloat32(tc.in); got != tc.want {
+				t.Errorf("Float16FromFloat32(%v) = %#04x, expecting %#04x", tc.in, uint16(got), uint16(tc.want))
+			}
+		})
+	}
+}
+
+func TestFloat16Float32(t *testing.T) {
+	tests := []struct {
+		in   Float16
+		want float32
+	}{
+		{0x0000, 0},
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x7bff, 65504},
+		{0x0400, float32(math.Ldexp(1, -14))},
+		{0x0001, float32(math.Ldexp(1, -24))},
+		{0x03ff, float32(math.Ldexp(1023, -24))},
+		{0x7c00, float32(math.Inf(1))},
+		{0xfc00, float32(math.Inf(-1))},
+	}
+	for _, tc := range tests {
+		if got := tc.in.Float32(); got != tc.want {
+			t.Errorf("Float16(%#04x).Float32() = %v, expecting %v", uint16(tc.in), got, tc.want)
+		}
+	}
+	if got := Float16(0x7e00).Float32(); !math.IsNaN(float64(got)) {
+		t.Errorf("Float16(0x7e00).Float32() = %v, expecting NaN", got)
+	}
+}
+
+// every Float16 but NaN converts to float32 and back exactly
+func TestFloat16RoundTrip(t *testing.T) {
+	for ii := 0; ii <= math.MaxUint16; ii++ {
+		h := Float16(ii)
+		f := h.Float32()
+		if math.IsNaN(float64(f)) {
+			if back := Float16FromFloat32(f); back&0x7c00 != 0x7c00 || back&0x3ff == 0 {
+				t.Errorf("NaN %#04x converted back to %#04x", ii, uint16(back))
+			}
+			continue
+		}
+		if back := Float16FromFloat32(f); back != h {
+			t.Errorf("%#04x converted to %v and back to %#04x", ii, f, uint16(back))
+		}
+	}
+}